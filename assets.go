@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+	"golang.org/x/exp/slices"
+)
+
+// fingerprintLength is the number of hash characters added to fingerprinted asset names
+const fingerprintLength = 8
+
+// assetSourcePath returns the absolute path of the asset linked from the page
+func assetSourcePath(p parsedPage, link string) string {
+	return filepath.Clean(filepath.Join(filepath.Dir(p.originalPath), link))
+}
+
+// fingerprintedName adds the hash to the file name before its extension (`image.png` -> `image.1a2b3c4d.png`)
+func fingerprintedName(name, hash string) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash[:fingerprintLength] + ext
+}
+
+/*
+assetDestinations decides where each asset is exported, the destinations are relative to the assets folder
+Assets from the graph `assets/` folder keep their relative path (`assets/sub/image.png` -> `sub/image.png`),
+other assets are exported by their file name. If two different assets end up with the same destination,
+both get their content hash in the name. With fingerprint, all assets get their content hash in the name.
+Processed images (widths are the imageWidths, nil without image processing) also claim the names of their variants
+(`photo-480w.jpg`), so a variant never overwrites another asset.
+*/
+func assetDestinations(appFS afero.Fs, logseqFolder string, sources []string, fingerprint bool, widths []int) map[string]string {
+	graphAssetsFolder := filepath.Join(logseqFolder, "assets")
+	hashes := map[string]string{}
+	contentHash := func(src string) string {
+		if hash, ok := hashes[src]; ok {
+			return hash
+		}
+		content, err := afero.ReadFile(appFS, src)
+		if err != nil {
+			// the copying logs the missing asset
+			hashes[src] = ""
+			return ""
+		}
+		hashes[src] = hashBytes(content)
+		return hashes[src]
+	}
+
+	destinations := map[string]string{}
+	sourcesByDest := map[string][]string{}
+	claim := func(dest, src string) {
+		if !slices.Contains(sourcesByDest[dest], src) {
+			sourcesByDest[dest] = append(sourcesByDest[dest], src)
+		}
+	}
+	for _, src := range sources {
+		dest := filepath.Base(src)
+		if rel, err := filepath.Rel(graphAssetsFolder, src); err == nil && !strings.HasPrefix(rel, "..") {
+			dest = rel
+		}
+		destinations[src] = dest
+		claim(dest, src)
+		if isProcessedImage(src) {
+			for _, width := range widths {
+				claim(variantName(dest, width), src)
+			}
+		}
+	}
+
+	colliding := map[string]bool{}
+	for dest, srcs := range sourcesByDest {
+		if len(srcs) > 1 {
+			log.Printf("assets %s have the same name %q, adding their content hash to the name", strings.Join(srcs, ", "), dest)
+			for _, src := range srcs {
+				colliding[src] = true
+			}
+		}
+	}
+	for _, src := range sources {
+		if !fingerprint && !colliding[src] {
+			continue
+		}
+		if hash := contentHash(src); hash != "" {
+			destinations[src] = fingerprintedName(destinations[src], hash)
+		}
+	}
+	return destinations
+}
+
+// exportedAsset is an asset linked from the exported pages
+type exportedAsset struct {
+	// dest is relative to the output folder
+	dest string
+	url  string
+	// missing is true if the linked file doesn't exist
+	missing bool
+	// image is set if the asset is a processed image, width is the width of the processed image
+	image    *imageOptions
+	width    int
+	variants []imageVariant
+}
+
+/*
+collectAssets finds all assets linked from the exported pages and decides their destination and URL
+The result is keyed by the absolute path of the asset.
+*/
+func collectAssets(appFS afero.Fs, t target, config *Config, exportPages []parsedPage) map[string]exportedAsset {
+	// get all asset paths (deduplicated)
+	assetFullPaths := map[string]struct{}{}
+	for _, page := range exportPages {
+		for _, assetPath := range page.pc.assets {
+			assetFullPaths[assetSourcePath(page, assetPath)] = struct{}{}
+		}
+	}
+	sources := make([]string, 0, len(assetFullPaths))
+	for fullPath := range assetFullPaths {
+		sources = append(sources, fullPath)
+	}
+	sort.Strings(sources)
+
+	assets := map[string]exportedAsset{}
+	opts := newImageOptions(config)
+	var widths []int
+	if opts != nil {
+		widths = opts.widths
+	}
+	destinations := assetDestinations(appFS, config.LogseqFolder, sources, config.FingerprintAssets, widths)
+	for _, src := range sources {
+		_, err := appFS.Stat(src)
+		asset := exportedAsset{
+			dest:    filepath.Join(t.assetsFolder(), destinations[src]),
+			url:     t.assetURL(filepath.ToSlash(destinations[src])),
+			missing: err != nil,
+		}
+		if opts != nil && !asset.missing && isProcessedImage(src) {
+			planImage(appFS, t, src, destinations[src], &asset, opts)
+		}
+		assets[src] = asset
+	}
+	return assets
+}
+
+// planImage decides the width and variants of the processed image, images that can't be decoded are copied
+func planImage(appFS afero.Fs, t target, src, destination string, asset *exportedAsset, opts *imageOptions) {
+	content, err := afero.ReadFile(appFS, src)
+	if err == nil {
+		asset.width, err = imageWidth(content, *opts)
+	}
+	if err != nil {
+		log.Printf("copying image %q without processing: %v", src, err)
+		return
+	}
+	asset.image = opts
+	for _, width := range variantWidths(asset.width, *opts) {
+		name := variantName(destination, width)
+		asset.variants = append(asset.variants, imageVariant{
+			dest:  filepath.Join(t.assetsFolder(), name),
+			url:   t.assetURL(filepath.ToSlash(name)),
+			width: width,
+		})
+	}
+}
+
+// exportAssets copies all assets that exist to the output folder, processed images are written with all their variants
+func exportAssets(writer *outputWriter, assets map[string]exportedAsset) {
+	sources := make([]string, 0, len(assets))
+	for src := range assets {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+	for _, src := range sources {
+		asset := assets[src]
+		if asset.missing {
+			continue
+		}
+		if asset.image == nil {
+			if err := writer.copyAsset(src, asset.dest); err != nil {
+				log.Printf("failed copying asset from %q to %q: %v", src, asset.dest, err)
+			}
+			continue
+		}
+		opts := *asset.image
+		if err := writer.convertAsset(src, asset.dest, func(content []byte) ([]byte, error) { return processImage(content, opts, 0) }); err != nil {
+			log.Printf("failed processing image from %q to %q: %v", src, asset.dest, err)
+		}
+		for _, v := range asset.variants {
+			width := v.width
+			if err := writer.convertAsset(src, v.dest, func(content []byte) ([]byte, error) { return processImage(content, opts, width) }); err != nil {
+				log.Printf("failed processing image from %q to %q: %v", src, v.dest, err)
+			}
+		}
+	}
+}
+
+const (
+	mediaPDF   = "pdf"
+	mediaVideo = "video"
+	mediaAudio = "audio"
+)
+
+var mediaExtensions = map[string]string{
+	".pdf":  mediaPDF,
+	".mp4":  mediaVideo,
+	".webm": mediaVideo,
+	".ogv":  mediaVideo,
+	".mov":  mediaVideo,
+	".mp3":  mediaAudio,
+	".wav":  mediaAudio,
+	".ogg":  mediaAudio,
+	".m4a":  mediaAudio,
+	".flac": mediaAudio,
+}
+
+/*
+htmlMedia renders an embedded PDF, video, or audio player
+The players contain a download link for browsers that can't play the file.
+*/
+func htmlMedia(kind, url, name string) string {
+	url = html.EscapeString(url)
+	link := fmt.Sprintf(`<a href="%s">%s</a>`, url, html.EscapeString(name))
+	switch kind {
+	case mediaPDF:
+		return fmt.Sprintf(`<object data="%s" type="application/pdf" width="100%%" height="600">%s</object>`, url, link)
+	case mediaVideo:
+		return fmt.Sprintf(`<video controls src="%s">%s</video>`, url, link)
+	case mediaAudio:
+		return fmt.Sprintf(`<audio controls src="%s">%s</audio>`, url, link)
+	}
+	return link
+}
+
+/*
+replaceAssetPaths replaces the relative asset references with URLs of the exported assets
+Images and links keep the markdown syntax, media macros (`{{pdf}}`, `{{video}}`, `{{audio}}`)
+and images of PDF, video, and audio files (Logseq embeds them) become players rendered by the target.
+Processed images with variants become images of the target with `srcset`. Logseq attributes (`{:width 400}`) of the players are removed.
+Links to missing assets are kept, removed, or replaced with the missingAssetPlaceholder based on the missingAssets option.
+*/
+func replaceAssetPaths(p parsedPage, assets map[string]exportedAsset, t target, config *Config) string {
+	return assetRegexp.ReplaceAllStringFunc(p.pc.content, func(match string) string {
+		submatches := assetRegexp.FindStringSubmatch(match)
+		link := assetLink(submatches)
+		asset, ok := assets[assetSourcePath(p, link)]
+		if !ok {
+			return match
+		}
+		if asset.missing {
+			switch config.MissingAssets {
+			case missingAssetsRemove:
+				return ""
+			case missingAssetsPlaceholder:
+				return config.MissingAssetPlaceholder
+			}
+		}
+		image, text, attributes, macro := submatches[1] != "", submatches[2], submatches[4], submatches[5]
+		if macro != "" {
+			return t.media(macro, asset.url, filepath.Base(link))
+		}
+		if kind, ok := mediaExtensions[strings.ToLower(filepath.Ext(link))]; ok && image {
+			if text == "" {
+				text = filepath.Base(link)
+			}
+			return t.media(kind, asset.url, text)
+		}
+		if image && len(asset.variants) > 0 {
+			return renderResponsiveImage(t, text, asset, parseImageSize(attributes))
+		}
+		// replaceImageSizes converts the attributes later
+		return fmt.Sprintf("%s[%s](%s)%s", submatches[1], text, asset.url, attributes)
+	})
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssetDestinations(t *testing.T) {
+	appFS := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(appFS, "/graph/assets/image.png", []byte("first"), 0644))
+	require.NoError(t, afero.WriteFile(appFS, "/graph/assets/sub/image.png", []byte("second"), 0644))
+	require.NoError(t, afero.WriteFile(appFS, "/graph/pages/image.png", []byte("third"), 0644))
+	firstHash := hashBytes([]byte("first"))[:fingerprintLength]
+	thirdHash := hashBytes([]byte("third"))[:fingerprintLength]
+
+	t.Run("keeps paths relative to the graph assets folder", func(t *testing.T) {
+		destinations := assetDestinations(appFS, "/graph", []string{"/graph/assets/image.png", "/graph/assets/sub/image.png"}, false, nil)
+		require.Equal(t, map[string]string{
+			"/graph/assets/image.png":     "image.png",
+			"/graph/assets/sub/image.png": filepath.Join("sub", "image.png"),
+		}, destinations)
+	})
+
+	t.Run("adds content hash to colliding names", func(t *testing.T) {
+		destinations := assetDestinations(appFS, "/graph", []string{"/graph/assets/image.png", "/graph/pages/image.png"}, false, nil)
+		require.Equal(t, map[string]string{
+			"/graph/assets/image.png": "image." + firstHash + ".png",
+			"/graph/pages/image.png":  "image." + thirdHash + ".png",
+		}, destinations)
+	})
+
+	t.Run("fingerprints all assets", func(t *testing.T) {
+		destinations := assetDestinations(appFS, "/graph", []string{"/graph/assets/image.png", "/graph/assets/missing.png"}, true, nil)
+		require.Equal(t, map[string]string{
+			"/graph/assets/image.png":   "image." + firstHash + ".png",
+			"/graph/assets/missing.png": "missing.png",
+		}, destinations)
+	})
+
+	t.Run("adds content hash to assets colliding with image variants", func(t *testing.T) {
+		require.NoError(t, afero.WriteFile(appFS, "/graph/assets/photo.jpg", []byte("photo"), 0644))
+		require.NoError(t, afero.WriteFile(appFS, "/graph/assets/photo-480w.jpg", []byte("variant"), 0644))
+		sources := []string{"/graph/assets/photo.jpg", "/graph/assets/photo-480w.jpg"}
+		require.Equal(t, map[string]string{
+			"/graph/assets/photo.jpg":      "photo." + hashBytes([]byte("photo"))[:fingerprintLength] + ".jpg",
+			"/graph/assets/photo-480w.jpg": "photo-480w." + hashBytes([]byte("variant"))[:fingerprintLength] + ".jpg",
+		}, assetDestinations(appFS, "/graph", sources, false, []int{480}))
+		require.Equal(t, map[string]string{
+			"/graph/assets/photo.jpg":      "photo.jpg",
+			"/graph/assets/photo-480w.jpg": "photo-480w.jpg",
+		}, assetDestinations(appFS, "/graph", sources, false, nil))
+	})
+}
+
+func TestExportAssets(t *testing.T) {
+	appFS := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(appFS, "/graph/assets/image.png", []byte("first"), 0644))
+	require.NoError(t, afero.WriteFile(appFS, "/graph/assets/sub/image.png", []byte("second"), 0644))
+	config := testConfig()
+	config.LogseqFolder = "/graph"
+	config.OutputFolder = "/out"
+	writer, err := newOutputWriter(appFS, "/out", config)
+	require.NoError(t, err)
+	page := parsedPage{
+		originalPath: "/graph/pages/a.md",
+		pc: parsedContent{
+			content: "![first](../assets/image.png) ![second](../assets/sub/image.png) ![missing](../assets/missing.png)",
+			assets:  []string{"../assets/image.png", "../assets/sub/image.png", "../assets/missing.png"},
+		},
+	}
+
+	assets := collectAssets(appFS, testTarget(), config, []parsedPage{page})
+	exportAssets(writer, assets)
+
+	require.Equal(t, map[string]exportedAsset{
+		"/graph/assets/image.png":     {dest: filepath.Join("logseq-assets", "image.png"), url: "/logseq-assets/image.png"},
+		"/graph/assets/sub/image.png": {dest: filepath.Join("logseq-assets", "sub", "image.png"), url: "/logseq-assets/sub/image.png"},
+		"/graph/assets/missing.png":   {dest: filepath.Join("logseq-assets", "missing.png"), url: "/logseq-assets/missing.png", missing: true},
+	}, assets)
+	content, err := afero.ReadFile(appFS, "/out/logseq-assets/sub/image.png")
+	require.NoError(t, err)
+	require.Equal(t, "second", string(content))
+	_, err = appFS.Stat("/out/logseq-assets/missing.png")
+	require.Error(t, err)
+
+	t.Run("replaces links with asset URLs", func(t *testing.T) {
+		require.Equal(t, "![first](/logseq-assets/image.png) ![second](/logseq-assets/sub/image.png) ![missing](/logseq-assets/missing.png)", replaceAssetPaths(page, assets, testTarget(), config))
+	})
+
+	t.Run("removes links to missing assets", func(t *testing.T) {
+		config := testConfig()
+		config.MissingAssets = missingAssetsRemove
+		require.Equal(t, "![first](/logseq-assets/image.png) ![second](/logseq-assets/sub/image.png) ", replaceAssetPaths(page, assets, testTarget(), config))
+	})
+
+	t.Run("replaces links to missing assets with placeholder", func(t *testing.T) {
+		config := testConfig()
+		config.MissingAssets = missingAssetsPlaceholder
+		require.Equal(t, "![first](/logseq-assets/image.png) ![second](/logseq-assets/sub/image.png) (missing image)", replaceAssetPaths(page, assets, testTarget(), config))
+	})
+}
+
+func TestReplaceMediaAssets(t *testing.T) {
+	// astro renders HTML players, see TestTargetMedia for the other targets
+	config := testConfig()
+	config.Target = targetAstro
+	target, err := newTarget(config)
+	require.NoError(t, err)
+	assets := map[string]exportedAsset{}
+	for _, name := range []string{"paper.pdf", "clip.mp4", "song.mp3", "notes.txt"} {
+		assets["/graph/assets/"+name] = exportedAsset{url: "/logseq-assets/" + name}
+	}
+	testCases := []struct {
+		desc     string
+		content  string
+		expected string
+	}{
+		{desc: "file link", content: "[notes](../assets/notes.txt)", expected: "[notes](/logseq-assets/notes.txt)"},
+		{desc: "pdf link", content: "[paper](../assets/paper.pdf)", expected: "[paper](/logseq-assets/paper.pdf)"},
+		{
+			desc:     "pdf macro",
+			content:  "{{pdf ../assets/paper.pdf}}",
+			expected: `<object data="/logseq-assets/paper.pdf" type="application/pdf" width="100%" height="600"><a href="/logseq-assets/paper.pdf">paper.pdf</a></object>`,
+		},
+		{
+			desc:     "embedded pdf",
+			content:  "![my paper](../assets/paper.pdf)",
+			expected: `<object data="/logseq-assets/paper.pdf" type="application/pdf" width="100%" height="600"><a href="/logseq-assets/paper.pdf">my paper</a></object>`,
+		},
+		{
+			desc:     "video macro",
+			content:  "{{video ../assets/clip.mp4}}",
+			expected: `<video controls src="/logseq-assets/clip.mp4"><a href="/logseq-assets/clip.mp4">clip.mp4</a></video>`,
+		},
+		{
+			desc:     "embedded audio",
+			content:  "![](../assets/song.mp3)",
+			expected: `<audio controls src="/logseq-assets/song.mp3"><a href="/logseq-assets/song.mp3">song.mp3</a></audio>`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			page := parsedPage{originalPath: "/graph/pages/a.md", pc: parsedContent{content: tC.content}}
+			require.Equal(t, tC.expected, replaceAssetPaths(page, assets, target, config))
+		})
+	}
+}
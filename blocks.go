@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+)
+
+/* indexedBlock is a block that can be referenced from other pages by its `id::` property */
+type indexedBlock struct {
+	block *block
+	// page contains the block
+	page textFile
+	// public is true if the block is on a public page
+	public bool
+}
+
+/*
+buildBlockIndex finds all blocks with the `id::` block property in all pages (including the non-public ones)
+Blocks on public pages are not public if they (or their parents) are private blocks.
+*/
+func buildBlockIndex(pages []textFile, config *Config, private privateBlockMatcher) map[string]indexedBlock {
+	index := map[string]indexedBlock{}
+	for _, page := range pages {
+		indexBlocks(index, parseOutline(stripAttributes(page.content)).blocks, page, isPublic(page, config), private)
+	}
+	return index
+}
+
+func indexBlocks(index map[string]indexedBlock, blocks []*block, page textFile, public bool, private privateBlockMatcher) {
+	for _, b := range blocks {
+		blockPublic := public && !private.isPrivate(b)
+		if id, ok := b.properties["id"]; ok {
+			index[strings.ToLower(id)] = indexedBlock{
+				block:  b,
+				page:   page,
+				public: blockPublic,
+			}
+		}
+		indexBlocks(index, b.children, page, blockPublic, private)
+	}
+}
+
+/* privateBlockMatcher finds private blocks, the export creates it once so that the tag regexp is compiled once */
+type privateBlockMatcher struct {
+	property string
+	// tag is nil if there is no privateBlockTag
+	tag *regexp.Regexp
+}
+
+func newPrivateBlockMatcher(config *Config) privateBlockMatcher {
+	m := privateBlockMatcher{property: config.PrivateBlockProperty}
+	if config.PrivateBlockTag != "" {
+		tag := regexp.QuoteMeta(config.PrivateBlockTag)
+		m.tag = regexp.MustCompile(fmt.Sprintf(`(?i)(?:^|\s)#(?:%s|\[\[%s]])(?:$|[\s.,;:!?)])`, tag, tag))
+	}
+	return m
+}
+
+/*
+isPrivate returns true if the block has the privateBlockProperty set to true (`private:: true`)
+or if it contains the privateBlockTag (`#private` or `#[[private]]`)
+*/
+func (m privateBlockMatcher) isPrivate(b *block) bool {
+	if m.property != "" && strings.EqualFold(b.properties[m.property], "true") {
+		return true
+	}
+	if m.tag == nil {
+		return false
+	}
+	for _, line := range b.content {
+		if m.tag.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+redactPrivateBlocks removes private blocks with all their children from the outline
+and returns the number of removed blocks
+*/
+func redactPrivateBlocks(o *outline, private privateBlockMatcher) int {
+	redacted := 0
+	o.blocks = redactBlocks(o.blocks, private, &redacted)
+	return redacted
+}
+
+func redactBlocks(blocks []*block, private privateBlockMatcher, redacted *int) []*block {
+	result := make([]*block, 0, len(blocks))
+	for _, b := range blocks {
+		if private.isPrivate(b) {
+			walkBlocks([]*block{b}, func(*block) { *redacted++ })
+			continue
+		}
+		b.children = redactBlocks(b.children, private, redacted)
+		result = append(result, b)
+	}
+	return result
+}
+
+var blockRefRegexp = regexp.MustCompile(`\(\(([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})\)\)`)
+
+// resolveOutlineBlockRefs resolves block references in all blocks of the outline
+func resolveOutlineBlockRefs(o *outline, index map[string]indexedBlock, config *Config) {
+	walkBlocks(o.blocks, func(b *block) {
+		for i, line := range b.content {
+			b.content[i] = resolveBlockRefs(line, index, config)
+		}
+	})
+}
+
+/*
+resolveBlockRefs replaces block references `((64c4f1a2-...))` with the text of the referenced block.
+References to blocks on non-public pages are handled based on the privateBlockRefs config.
+*/
+func resolveBlockRefs(content string, index map[string]indexedBlock, config *Config) string {
+	return resolveBlockRefsRecursively(content, index, config, map[string]bool{})
+}
+
+func resolveBlockRefsRecursively(content string, index map[string]indexedBlock, config *Config, visited map[string]bool) string {
+	return blockRefRegexp.ReplaceAllStringFunc(content, func(ref string) string {
+		id := strings.ToLower(blockRefRegexp.FindStringSubmatch(ref)[1])
+		b, ok := index[id]
+		if !ok {
+			log.Printf("block reference %s doesn't point to any block with the `id::` property", ref)
+			return ref
+		}
+		if visited[id] {
+			// the referenced block (indirectly) references itself
+			return ref
+		}
+		if !b.public {
+			switch config.PrivateBlockRefs {
+			case privateBlockRefsRemove:
+				return ""
+			case privateBlockRefsPlaceholder:
+				return config.PrivateBlockRefPlaceholder
+			}
+		}
+		visited[id] = true
+		defer delete(visited, id)
+		return resolveBlockRefsRecursively(b.block.text(), index, config, visited)
+	})
+}
+
+/*
+exposeBlockProperties adds block properties listed in the blockProperties config back to the exported content.
+All other block properties are removed from the content by parseOutline.
+
+  - block text
+    background-color:: yellow
+
+becomes a markdown attribute list (blockPropertiesFormat: attributes)
+
+	block text
+	{data-background-color="yellow"}
+
+or a shortcode (blockPropertiesFormat: shortcode)
+
+	{{< logseq-block background-color="yellow" >}}
+	block text
+	{{< /logseq-block >}}
+*/
+func exposeBlockProperties(o *outline, config *Config) {
+	if len(config.BlockProperties) == 0 {
+		return
+	}
+	walkBlocks(o.blocks, func(b *block) {
+		if !b.hasText() {
+			return
+		}
+		var params []string
+		for _, name := range config.BlockProperties {
+			value, ok := b.properties[name]
+			if !ok {
+				continue
+			}
+			if config.BlockPropertiesFormat == blockPropertiesShortcode {
+				params = append(params, fmt.Sprintf("%s=%q", name, value))
+			} else {
+				params = append(params, fmt.Sprintf(`data-%s="%s"`, name, html.EscapeString(value)))
+			}
+		}
+		if len(params) == 0 {
+			return
+		}
+		// trailing empty lines separate the block from the next one, we keep them at the end
+		end := len(b.content)
+		for end > 0 && b.content[end-1] == "" {
+			end--
+		}
+		text, trailing := b.content[:end], b.content[end:]
+		var content []string
+		if config.BlockPropertiesFormat == blockPropertiesShortcode {
+			content = append(content, fmt.Sprintf("{{< %s %s >}}", config.BlockPropertiesShortcode, strings.Join(params, " ")))
+			content = append(content, text...)
+			content = append(content, fmt.Sprintf("{{< /%s >}}", config.BlockPropertiesShortcode))
+		} else {
+			content = append(content, text...)
+			content = append(content, fmt.Sprintf("{%s}", strings.Join(params, " ")))
+		}
+		b.content = append(content, trailing...)
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveBlockRefs(t *testing.T) {
+	pages := []textFile{
+		{content: "public:: true\n\n- public block\n  id:: 64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f"},
+		{content: "- private block\n  id:: 64c4f1a2-1111-4a3f-9a3c-7a9b2d7c1e3f"},
+		{content: "public:: true\n\n- nested ((64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f))\n  id:: 64c4f1a2-2222-4a3f-9a3c-7a9b2d7c1e3f"},
+		{content: "public:: true\n\n- cycle ((64c4f1a2-3333-4a3f-9a3c-7a9b2d7c1e3f))\n  id:: 64c4f1a2-3333-4a3f-9a3c-7a9b2d7c1e3f"},
+	}
+	index := buildBlockIndex(pages, testConfig(), newPrivateBlockMatcher(testConfig()))
+
+	t.Run("inlines text of public blocks", func(t *testing.T) {
+		result := resolveBlockRefs("- see ((64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f))", index, testConfig())
+		require.Equal(t, "- see public block", result)
+	})
+
+	t.Run("resolves references in referenced blocks", func(t *testing.T) {
+		result := resolveBlockRefs("- see ((64c4f1a2-2222-4a3f-9a3c-7a9b2d7c1e3f))", index, testConfig())
+		require.Equal(t, "- see nested public block", result)
+	})
+
+	t.Run("stops on cyclic references", func(t *testing.T) {
+		result := resolveBlockRefs("- ((64c4f1a2-3333-4a3f-9a3c-7a9b2d7c1e3f))", index, testConfig())
+		require.Equal(t, "- cycle ((64c4f1a2-3333-4a3f-9a3c-7a9b2d7c1e3f))", result)
+	})
+
+	t.Run("keeps unknown references", func(t *testing.T) {
+		result := resolveBlockRefs("- ((64c4f1a2-9999-4a3f-9a3c-7a9b2d7c1e3f))", index, testConfig())
+		require.Equal(t, "- ((64c4f1a2-9999-4a3f-9a3c-7a9b2d7c1e3f))", result)
+	})
+
+	t.Run("handles references to private blocks", func(t *testing.T) {
+		content := "- see ((64c4f1a2-1111-4a3f-9a3c-7a9b2d7c1e3f))"
+		inline := resolveBlockRefs(content, index, &Config{PrivateBlockRefs: privateBlockRefsInline})
+		require.Equal(t, "- see private block", inline)
+		removed := resolveBlockRefs(content, index, &Config{PrivateBlockRefs: privateBlockRefsRemove})
+		require.Equal(t, "- see ", removed)
+		placeholder := resolveBlockRefs(content, index, &Config{
+			PrivateBlockRefs:           privateBlockRefsPlaceholder,
+			PrivateBlockRefPlaceholder: "(hidden)",
+		})
+		require.Equal(t, "- see (hidden)", placeholder)
+	})
+}
+
+func TestResolveOutlineBlockRefs(t *testing.T) {
+	index := buildBlockIndex([]textFile{
+		{content: "public:: true\n\n- referenced text\n  collapsed:: true\n  id:: 64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f"},
+	}, testConfig(), newPrivateBlockMatcher(testConfig()))
+	o := parseOutline("- parent\n\t- child ((64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f))")
+	resolveOutlineBlockRefs(&o, index, testConfig())
+	require.Equal(t, "child referenced text", o.blocks[0].children[0].content[0])
+}
+
+func TestExposeBlockProperties(t *testing.T) {
+	content := "- text\n  background-color:: yellow\n  id:: 64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f\n\t- child\n\t  heading:: true\n"
+
+	t.Run("removes block properties by default", func(t *testing.T) {
+		o := parseOutline(content)
+		exposeBlockProperties(&o, testConfig())
+		require.Equal(t, "\ntext\n\n- child\n", o.render())
+	})
+
+	t.Run("adds allowed properties as attributes", func(t *testing.T) {
+		o := parseOutline(content)
+		exposeBlockProperties(&o, &Config{
+			BlockProperties:       []string{"heading", "background-color"},
+			BlockPropertiesFormat: blockPropertiesAttributes,
+		})
+		require.Equal(t, "\ntext\n{data-background-color=\"yellow\"}\n\n- child\n  {data-heading=\"true\"}\n", o.render())
+	})
+
+	t.Run("escapes attribute values as HTML", func(t *testing.T) {
+		o := parseOutline("- text\n  title:: \"quoted\" & ünicode")
+		exposeBlockProperties(&o, &Config{
+			BlockProperties:       []string{"title"},
+			BlockPropertiesFormat: blockPropertiesAttributes,
+		})
+		require.Equal(t, "\ntext\n{data-title=\"&#34;quoted&#34; &amp; ünicode\"}", o.render())
+	})
+
+	t.Run("wraps blocks with allowed properties in a shortcode", func(t *testing.T) {
+		o := parseOutline(content)
+		exposeBlockProperties(&o, &Config{
+			BlockProperties:          []string{"background-color"},
+			BlockPropertiesFormat:    blockPropertiesShortcode,
+			BlockPropertiesShortcode: "block",
+		})
+		require.Equal(t, "\n{{< block background-color=\"yellow\" >}}\ntext\n{{< /block >}}\n\n- child\n", o.render())
+	})
+}
+
+func TestRedactPrivateBlocks(t *testing.T) {
+	config := testConfig()
+	config.PrivateBlockTag = "secret"
+
+	t.Run("removes private blocks with their children", func(t *testing.T) {
+		o := parseOutline("- public\n- private block\n  private:: true\n\t- child\n\t\t- grandchild\n- public again")
+		redacted := redactPrivateBlocks(&o, newPrivateBlockMatcher(config))
+		require.Equal(t, 3, redacted)
+		require.Equal(t, "\npublic\n\npublic again", o.render())
+	})
+
+	t.Run("removes blocks with the private tag", func(t *testing.T) {
+		o := parseOutline("- parent\n\t- tagged #secret\n\t- tagged #[[Secret]], too\n\t- #secretive is not the tag\n\t- not#secret")
+		redacted := redactPrivateBlocks(&o, newPrivateBlockMatcher(config))
+		require.Equal(t, 2, redacted)
+		require.Equal(t, "\nparent\n\n- #secretive is not the tag\n- not#secret", o.render())
+	})
+
+	t.Run("keeps blocks with private:: false", func(t *testing.T) {
+		o := parseOutline("- block\n  private:: false")
+		require.Equal(t, 0, redactPrivateBlocks(&o, newPrivateBlockMatcher(config)))
+	})
+
+	t.Run("marks private blocks in block index as non-public", func(t *testing.T) {
+		index := buildBlockIndex([]textFile{
+			{content: "public:: true\n\n- private:: true\n\t- child\n\t  id:: 64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f\n- public\n  id:: 64c4f1a2-1111-4a3f-9a3c-7a9b2d7c1e3f"},
+		}, config, newPrivateBlockMatcher(config))
+		require.False(t, index["64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f"].public)
+		require.True(t, index["64c4f1a2-1111-4a3f-9a3c-7a9b2d7c1e3f"].public)
+	})
+}
@@ -13,10 +13,110 @@ import (
 	"github.com/knadh/koanf/v2"
 )
 
+const (
+	privateBlockRefsInline      = "inline"
+	privateBlockRefsRemove      = "remove"
+	privateBlockRefsPlaceholder = "placeholder"
+)
+
+const (
+	unpublishedLinksKeep = "keep"
+	unpublishedLinksText = "text"
+	unpublishedLinksSpan = "span"
+	unpublishedLinksFail = "fail"
+)
+
+const (
+	backlinksNone        = "none"
+	backlinksFrontMatter = "frontmatter"
+	backlinksSection     = "section"
+)
+
+const (
+	tagLinksKeep     = "keep"
+	tagLinksPage     = "page"
+	tagLinksTaxonomy = "taxonomy"
+)
+
+const (
+	missingAssetsKeep        = "keep"
+	missingAssetsRemove      = "remove"
+	missingAssetsPlaceholder = "placeholder"
+)
+
+const (
+	blockPropertiesAttributes = "attributes"
+	blockPropertiesShortcode  = "shortcode"
+)
+
 type Config struct {
-	LogseqFolder       string
-	OutputFolder       string
-	UnquotedProperties []string
+	LogseqFolder string
+	OutputFolder string
+	// Clean removes all files created by the previous export before exporting
+	Clean bool `json:"-"`
+	// Watch re-exports the graph every time it changes
+	Watch bool `json:"-"`
+	// DryRun prints what the export would change in the OutputFolder without writing anything
+	DryRun bool `koanf:"dry-run" json:"-"`
+	// Diff prints the differences of changed pages in DryRun
+	Diff bool `json:"-"`
+	// Strict fails the export if any page links to a missing asset or to a page that is not published
+	Strict bool `json:"-"`
+	// Target is the static site generator (hugo, jekyll, zola, astro) that decides the output layout and front matter format
+	Target string
+	// FrontMatter is the front matter format (yaml, toml, json), the default depends on the Target
+	FrontMatter string
+	// PagesRelativePath and AssetsRelativePath are folders in the OutputFolder, the default depends on the Target
+	PagesRelativePath  string
+	AssetsRelativePath string
+	// WebPagesPathPrefix and WebAssetsPathPrefix are URL prefixes of exported pages and assets, the default depends on the Target
+	WebPagesPathPrefix  string
+	WebAssetsPathPrefix string
+	// FingerprintAssets adds the content hash to the names of exported assets (`image.1a2b3c4d.png`)
+	FingerprintAssets bool
+	// ProcessImages re-encodes exported JPEG and PNG images, which removes their metadata (EXIF, GPS)
+	ProcessImages bool
+	// ImageMaxWidth and ImageMaxHeight limit the size of processed images, 0 means no limit
+	ImageMaxWidth  int
+	ImageMaxHeight int
+	// ImageQuality is the JPEG quality of processed images (1-100)
+	ImageQuality int
+	// ImageWidths are widths of smaller variants of processed images that are added to the image `srcset`
+	ImageWidths []int
+	// MissingAssets decides what happens with links to assets that don't exist (keep, remove, placeholder)
+	MissingAssets           string
+	MissingAssetPlaceholder string
+	// PropertyTypes overrides the automatically detected types of page properties (string, number, boolean, date, list)
+	PropertyTypes map[string]string
+	// PublicProperty is the page property that decides if the page gets exported
+	PublicProperty string
+	// PublicValues are the values of the PublicProperty that mean "export this page"
+	PublicValues []string
+	// AliasRedirects adds `aliases` front matter with URLs of all page aliases
+	AliasRedirects bool
+	// UnpublishedLinks decides what happens with [[links]] to pages that are not exported
+	UnpublishedLinks     string
+	UnpublishedLinkClass string
+	// Backlinks decides how the list of pages linking to the page gets exported
+	Backlinks        string
+	BacklinksHeading string
+	// MergeInlineTags adds `#tags` from the page content to the `tags` front matter
+	MergeInlineTags bool
+	// TagLinks decides if `#tags` become links to tag pages or to the static site taxonomy (TagURL)
+	TagLinks string
+	TagURL   string
+	// PrivateBlockRefs decides what happens with ((block references)) to blocks on non-public pages
+	PrivateBlockRefs           string
+	PrivateBlockRefPlaceholder string
+	// PrivateBlockProperty and PrivateBlockTag mark blocks that are removed from public pages
+	PrivateBlockProperty string
+	PrivateBlockTag      string
+	// EmbedPrivatePages allows {{embed}} macros to include content from non-public pages
+	EmbedPrivatePages bool
+	// BlockProperties lists block properties that are kept in the exported content, all other block properties are removed
+	BlockProperties          []string
+	BlockPropertiesFormat    string
+	BlockPropertiesShortcode string
 }
 
 func (c *Config) Validate() error {
@@ -26,6 +126,63 @@ func (c *Config) Validate() error {
 	if c.OutputFolder == "" {
 		return errors.New("outputFolder command line argument is mandatory ")
 	}
+	if c.PublicProperty == "" {
+		return errors.New("publicProperty can't be empty")
+	}
+	if _, err := newTarget(c); err != nil {
+		return err
+	}
+	if c.FrontMatter != "" {
+		if _, err := newFrontMatterEncoder(c.FrontMatter); err != nil {
+			return err
+		}
+	}
+	for name, propertyType := range c.PropertyTypes {
+		if err := validatePropertyType(propertyType); err != nil {
+			return fmt.Errorf("invalid type of the %q property: %w", name, err)
+		}
+	}
+	switch c.PrivateBlockRefs {
+	case privateBlockRefsInline, privateBlockRefsRemove, privateBlockRefsPlaceholder:
+	default:
+		return fmt.Errorf("privateBlockRefs has to be one of %q, %q, or %q, got %q", privateBlockRefsInline, privateBlockRefsRemove, privateBlockRefsPlaceholder, c.PrivateBlockRefs)
+	}
+	if c.ImageQuality < 1 || c.ImageQuality > 100 {
+		return fmt.Errorf("imageQuality has to be between 1 and 100, got %d", c.ImageQuality)
+	}
+	if c.ImageMaxWidth < 0 || c.ImageMaxHeight < 0 {
+		return fmt.Errorf("imageMaxWidth and imageMaxHeight can't be negative, got %d and %d", c.ImageMaxWidth, c.ImageMaxHeight)
+	}
+	for _, width := range c.ImageWidths {
+		if width <= 0 {
+			return fmt.Errorf("imageWidths have to be positive, got %d", width)
+		}
+	}
+	switch c.MissingAssets {
+	case missingAssetsKeep, missingAssetsRemove, missingAssetsPlaceholder:
+	default:
+		return fmt.Errorf("missingAssets has to be one of %q, %q, or %q, got %q", missingAssetsKeep, missingAssetsRemove, missingAssetsPlaceholder, c.MissingAssets)
+	}
+	switch c.UnpublishedLinks {
+	case unpublishedLinksKeep, unpublishedLinksText, unpublishedLinksSpan, unpublishedLinksFail:
+	default:
+		return fmt.Errorf("unpublishedLinks has to be one of %q, %q, %q, or %q, got %q", unpublishedLinksKeep, unpublishedLinksText, unpublishedLinksSpan, unpublishedLinksFail, c.UnpublishedLinks)
+	}
+	switch c.Backlinks {
+	case backlinksNone, backlinksFrontMatter, backlinksSection:
+	default:
+		return fmt.Errorf("backlinks has to be one of %q, %q, or %q, got %q", backlinksNone, backlinksFrontMatter, backlinksSection, c.Backlinks)
+	}
+	switch c.TagLinks {
+	case tagLinksKeep, tagLinksPage, tagLinksTaxonomy:
+	default:
+		return fmt.Errorf("tagLinks has to be one of %q, %q, or %q, got %q", tagLinksKeep, tagLinksPage, tagLinksTaxonomy, c.TagLinks)
+	}
+	switch c.BlockPropertiesFormat {
+	case blockPropertiesAttributes, blockPropertiesShortcode:
+	default:
+		return fmt.Errorf("blockPropertiesFormat has to be either %q or %q, got %q", blockPropertiesAttributes, blockPropertiesShortcode, c.BlockPropertiesFormat)
+	}
 	return nil
 }
 
@@ -33,11 +190,43 @@ func flagset() *flag.FlagSet {
 	f := flag.NewFlagSet("config", flag.ExitOnError)
 	f.String("logseqFolder", "", "[MANDATORY] Folder where all public pages are exported.")
 	f.String("outputFolder", "", "[MANDATORY] Folder where the transformed logseq pages will be stored.")
+	f.Bool("clean", false, "Remove all files created by the previous export before exporting.")
+	f.Bool("watch", false, "Watch the logseq folder and export the graph every time it changes.")
+	f.Bool("dry-run", false, "Print files that the export would create, modify, or delete without changing the output folder.")
+	f.Bool("diff", false, "With --dry-run, print the differences of the changed pages.")
+	f.Bool("strict", false, "Fail the export if any page links to a missing asset or to a page that is not published.")
 	return f
 }
 
+// defaultConfig contains default values for all optional configuration
+func defaultConfig() Config {
+	return Config{
+		Target:                     targetHugo,
+		PublicProperty:             "public",
+		PublicValues:               []string{"true"},
+		ImageQuality:               85,
+		MissingAssets:              missingAssetsKeep,
+		MissingAssetPlaceholder:    "(missing image)",
+		UnpublishedLinks:           unpublishedLinksKeep,
+		UnpublishedLinkClass:       "private-link",
+		Backlinks:                  backlinksNone,
+		BacklinksHeading:           "Linked references",
+		TagLinks:                   tagLinksKeep,
+		TagURL:                     "/tags/{tag}",
+		PrivateBlockRefs:           privateBlockRefsPlaceholder,
+		PrivateBlockRefPlaceholder: "(private block)",
+		PrivateBlockProperty:       "private",
+		PrivateBlockTag:            "private",
+		BlockPropertiesFormat:      blockPropertiesAttributes,
+		BlockPropertiesShortcode:   "logseq-block",
+	}
+}
+
 func parseConfig(args []string) (*Config, error) {
-	var config Config
+	config := defaultConfig()
+	// koanf would merge slices from the config file with the default values
+	// so we set the default slices only if they are missing in the config file
+	config.PublicValues = nil
 
 	f := flagset()
 
@@ -65,10 +254,16 @@ func parseConfig(args []string) (*Config, error) {
 	if err := k.Load(file.Provider(configPath), yaml.Parser()); err != nil {
 		log.Printf("Failed to read config file %q. Using default config.", configPath)
 	}
+	if k.Exists("unquotedProperties") {
+		log.Printf("unquotedProperties config option is ignored, property types are detected automatically and you can change them with the propertyTypes option")
+	}
 
 	if err := k.Unmarshal("", &config); err != nil {
 		return nil, fmt.Errorf("error unmarshal config: %w", err)
 	}
+	if config.PublicValues == nil {
+		config.PublicValues = defaultConfig().PublicValues
+	}
 
 	err := config.Validate()
 	if err != nil {
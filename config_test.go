@@ -29,8 +29,59 @@ func TestParseMandatoryFlags(t *testing.T) {
 		t.Fatalf("incorrectly parsed outputFolder. Expected %q got %q", "/path/to/logseq", config.OutputFolder)
 	}
 
-	if config.UnquotedProperties != nil {
-		t.Fatalf("incorrectly parsed unquotedProperties. Expected nil, got %v", config.UnquotedProperties)
+	if config.PropertyTypes != nil {
+		t.Fatalf("incorrectly parsed propertyTypes. Expected nil, got %v", config.PropertyTypes)
+	}
+
+	if config.PublicProperty != "public" || !reflect.DeepEqual(config.PublicValues, []string{"true"}) {
+		t.Fatalf("incorrect default public property. Expected public:: true, got %s:: %v", config.PublicProperty, config.PublicValues)
+	}
+
+	if config.Clean {
+		t.Fatalf("clean has to be false by default")
+	}
+
+	if config.PrivateBlockRefs != privateBlockRefsPlaceholder {
+		t.Fatalf("incorrect default privateBlockRefs. Expected %q, got %q", privateBlockRefsPlaceholder, config.PrivateBlockRefs)
+	}
+}
+
+func TestParseCleanFlag(t *testing.T) {
+	config, err := parseConfig([]string{
+		"script-name",
+		"--logseqFolder",
+		"/path/to/logseq",
+		"--outputFolder",
+		"/path/to/output",
+		"--clean",
+	})
+
+	if err != nil {
+		t.Fatalf("error when parsing config: %v", err)
+	}
+
+	if !config.Clean {
+		t.Fatalf("incorrectly parsed clean flag. Expected true, got false")
+	}
+}
+
+func TestParseDryRunFlags(t *testing.T) {
+	config, err := parseConfig([]string{
+		"script-name",
+		"--logseqFolder",
+		"/path/to/logseq",
+		"--outputFolder",
+		"/path/to/output",
+		"--dry-run",
+		"--diff",
+	})
+
+	if err != nil {
+		t.Fatalf("error when parsing config: %v", err)
+	}
+
+	if !config.DryRun || !config.Diff {
+		t.Fatalf("incorrectly parsed dry-run flags. Expected true, got dryRun %v and diff %v", config.DryRun, config.Diff)
 	}
 }
 
@@ -58,7 +109,84 @@ func TestTestParsingOptionalFlags(t *testing.T) {
 		t.Fatalf("incorrectly parsed outputFolder. Expected %q got %q", configFolderPath, config.OutputFolder)
 	}
 
-	if !reflect.DeepEqual(config.UnquotedProperties, []string{"date", "tags"}) {
-		t.Fatalf("incorrectly parsed unquotedProperties. Expected date, tags, got %v", config.UnquotedProperties)
+	if !reflect.DeepEqual(config.PropertyTypes, map[string]string{"rating": "number", "isbn": "string"}) {
+		t.Fatalf("incorrectly parsed propertyTypes. Expected rating: number, isbn: string, got %v", config.PropertyTypes)
+	}
+
+	if !reflect.DeepEqual(config.PublicValues, []string{"yes"}) {
+		t.Fatalf("incorrectly parsed publicValues. Expected yes, got %v", config.PublicValues)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	validConfig := func() Config {
+		config := defaultConfig()
+		config.LogseqFolder = "/path/to/logseq"
+		config.OutputFolder = "/path/to/output"
+		return config
+	}
+
+	config := validConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("expected default config to pass validation, got %v", err)
+	}
+
+	config.PrivateBlockRefs = "unknown"
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected unknown privateBlockRefs value to fail validation")
+	}
+
+	config = validConfig()
+	config.BlockPropertiesFormat = "unknown"
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected unknown blockPropertiesFormat value to fail validation")
+	}
+
+	config = validConfig()
+	config.Backlinks = "unknown"
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected unknown backlinks value to fail validation")
+	}
+
+	config = validConfig()
+	config.TagLinks = "unknown"
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected unknown tagLinks value to fail validation")
+	}
+
+	config = validConfig()
+	config.PropertyTypes = map[string]string{"rating": "unknown"}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected unknown property type to fail validation")
+	}
+
+	config = validConfig()
+	config.Target = "unknown"
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected unknown target value to fail validation")
+	}
+
+	config = validConfig()
+	config.ImageQuality = 0
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected imageQuality 0 to fail validation")
+	}
+
+	config = validConfig()
+	config.ImageWidths = []int{480, -1}
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected negative image width to fail validation")
+	}
+
+	config = validConfig()
+	config.MissingAssets = "unknown"
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected unknown missingAssets value to fail validation")
+	}
+
+	config = validConfig()
+	config.UnpublishedLinks = "unknown"
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected unknown unpublishedLinks value to fail validation")
 	}
 }
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strings"
+)
+
+var embedRegexp = regexp.MustCompile(`\{\{embed\s+(?:\[\[(.+?)]]|\(\(([0-9a-fA-F-]+)\)\))\s*}}`)
+
+/*
+buildPageIndex maps normalized page names and aliases to pages so that we can find embedded pages
+Logseq page names are case-insensitive.
+*/
+func buildPageIndex(pages []textFile) map[string]textFile {
+	index := map[string]textFile{}
+	for _, page := range pages {
+		attributes := parseAttributes(page.content)
+		index[normalizePageName(getTitle(page, attributes))] = page
+	}
+	for _, page := range pages {
+		for _, alias := range parseAliases(parseAttributes(page.content)) {
+			if _, ok := index[normalizePageName(alias)]; !ok {
+				index[normalizePageName(alias)] = page
+			}
+		}
+	}
+	return index
+}
+
+/*
+expandEmbeds replaces `{{embed [[page]]}}` and `{{embed ((block))}}` macros with the embedded blocks
+
+If the block contains only the embed macro, the embedded blocks take its place:
+
+  - {{embed [[page]]}}
+
+becomes
+
+  - first block of the page
+  - second block of the page
+
+Otherwise, the embedded blocks become children of the block containing the macro.
+*/
+func expandEmbeds(o *outline, title string, pageIndex map[string]textFile, blockIndex map[string]indexedBlock, config *Config) {
+	visited := map[string]bool{pageEmbedKey(title): true}
+	o.blocks = expandEmbedsInBlocks(o.blocks, pageIndex, blockIndex, config, visited)
+}
+
+// pageEmbedKey is the key of the page title, embeds of the page through its aliases have the same key
+func pageEmbedKey(name string) string {
+	return "[[" + normalizePageName(name) + "]]"
+}
+
+func blockEmbedKey(id string) string {
+	return "((" + strings.ToLower(id) + "))"
+}
+
+func expandEmbedsInBlocks(blocks []*block, pageIndex map[string]textFile, blockIndex map[string]indexedBlock, config *Config, visited map[string]bool) []*block {
+	result := make([]*block, 0, len(blocks))
+	for _, b := range blocks {
+		b.children = expandEmbedsInBlocks(b.children, pageIndex, blockIndex, config, visited)
+		var embedded []*block
+		for i, line := range b.content {
+			matches := embedRegexp.FindAllStringSubmatch(line, -1)
+			if matches == nil {
+				continue
+			}
+			for _, match := range matches {
+				embedded = append(embedded, embeddedBlocks(match, pageIndex, blockIndex, config, visited)...)
+			}
+			b.content[i] = strings.TrimSpace(embedRegexp.ReplaceAllString(line, ""))
+		}
+		if len(embedded) == 0 {
+			result = append(result, b)
+			continue
+		}
+		if !b.hasText() && len(b.children) == 0 {
+			// the block contained only the embed, the embedded blocks replace it
+			for _, e := range embedded {
+				e.setLevel(b.level)
+			}
+			result = append(result, embedded...)
+			continue
+		}
+		for _, e := range embedded {
+			e.setLevel(b.level + 1)
+		}
+		b.children = append(embedded, b.children...)
+		result = append(result, b)
+	}
+	return result
+}
+
+/*
+embeddedBlocks finds the page or block referenced by the embed macro
+and returns a copy of its (already expanded) blocks
+*/
+func embeddedBlocks(match []string, pageIndex map[string]textFile, blockIndex map[string]indexedBlock, config *Config, visited map[string]bool) []*block {
+	var key string
+	var public bool
+	var blocks []*block
+	if pageName := match[1]; pageName != "" {
+		page, ok := pageIndex[normalizePageName(pageName)]
+		if !ok {
+			log.Printf("embedded page %q doesn't exist", pageName)
+			return nil
+		}
+		key = pageEmbedKey(getTitle(page, parseAttributes(page.content)))
+		public = isPublic(page, config)
+		blocks = parseOutline(stripAttributes(page.content)).blocks
+	} else {
+		key = blockEmbedKey(match[2])
+		b, ok := blockIndex[strings.ToLower(match[2])]
+		if !ok {
+			log.Printf("embedded block %q doesn't exist", match[2])
+			return nil
+		}
+		public = b.public
+		blocks = []*block{b.block.copy()}
+	}
+	if visited[key] {
+		log.Printf("embed %s is not expanded because it embeds itself", match[0])
+		return nil
+	}
+	if !public && !config.EmbedPrivatePages {
+		log.Printf("embed %s is not expanded because it is not public (see the embedPrivatePages option)", match[0])
+		return nil
+	}
+	visited[key] = true
+	defer delete(visited, key)
+	return expandEmbedsInBlocks(blocks, pageIndex, blockIndex, config, visited)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandEmbeds(t *testing.T) {
+	pages := []textFile{
+		{absoluteFSPath: "/pages/public.md", content: "public:: true\n\n- first\n\t- nested\n- second"},
+		{absoluteFSPath: "/pages/private.md", content: "- private text"},
+		{absoluteFSPath: "/pages/blocks.md", content: "public:: true\n\n- parent\n  id:: 64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f\n\t- child\n- sibling"},
+		{absoluteFSPath: "/pages/cycle.md", content: "public:: true\n\n- cycle\n\t- {{embed [[cycle]]}}"},
+		{absoluteFSPath: "/pages/outer.md", content: "public:: true\n\n- outer\n\t- {{embed [[Public]]}}"},
+		{absoluteFSPath: "/pages/code.md", content: "public:: true\n\n- ```sh\n  echo hi\n  ```\n  id:: 64c4f1a2-4444-4a3f-9a3c-7a9b2d7c1e3f"},
+		{absoluteFSPath: "/pages/a.md", content: "alias:: b\npublic:: true\n\n- a text\n- {{embed [[b]]}}"},
+	}
+	pageIndex := buildPageIndex(pages)
+	blockIndex := buildBlockIndex(pages, testConfig(), newPrivateBlockMatcher(testConfig()))
+	expand := func(content string, config *Config) string {
+		o := parseOutline(content)
+		expandEmbeds(&o, "test", pageIndex, blockIndex, config)
+		return o.render()
+	}
+
+	t.Run("replaces block containing only the page embed", func(t *testing.T) {
+		result := expand("- intro\n\t- {{embed [[public]]}}\n- outro", testConfig())
+		require.Equal(t, "\nintro\n\n- first\n\t- nested\n- second\n\noutro", result)
+	})
+
+	t.Run("replaces top-level block with paragraphs", func(t *testing.T) {
+		result := expand("- {{embed [[public]]}}", testConfig())
+		require.Equal(t, "\nfirst\n\n- nested\n\nsecond", result)
+	})
+
+	t.Run("adds embedded blocks as children of a block with text", func(t *testing.T) {
+		result := expand("- see {{embed [[public]]}}", testConfig())
+		require.Equal(t, "\nsee\n\n- first\n\t- nested\n- second", result)
+	})
+
+	t.Run("embeds block with its children", func(t *testing.T) {
+		result := expand("- a\n\t- {{embed ((64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f))}}", testConfig())
+		require.Equal(t, "\na\n\n- parent\n\t- child", result)
+	})
+
+	t.Run("expands nested embeds", func(t *testing.T) {
+		result := expand("- {{embed [[outer]]}}", testConfig())
+		require.Equal(t, "\nouter\n\n- first\n\t- nested\n- second", result)
+	})
+
+	t.Run("embeds code blocks", func(t *testing.T) {
+		result := expand("- {{embed ((64c4f1a2-4444-4a3f-9a3c-7a9b2d7c1e3f))}}", testConfig())
+		require.Equal(t, "\n```sh\necho hi\n```", result)
+	})
+
+	t.Run("doesn't expand embed cycles", func(t *testing.T) {
+		result := expand("- {{embed [[cycle]]}}", testConfig())
+		require.Equal(t, "\ncycle\n", result)
+	})
+
+	t.Run("doesn't expand pages that embed themselves through an alias", func(t *testing.T) {
+		o := parseOutline("- a text\n- {{embed [[b]]}}")
+		expandEmbeds(&o, "a", pageIndex, blockIndex, testConfig())
+		require.Equal(t, "\na text\n", o.render())
+	})
+
+	t.Run("embeds private pages only when configured", func(t *testing.T) {
+		content := "- {{embed [[private]]}}"
+		require.Equal(t, "", expand(content, testConfig()))
+		config := testConfig()
+		config.EmbedPrivatePages = true
+		require.Equal(t, "\nprivate text", expand(content, config))
+	})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	frontMatterYAML = "yaml"
+	frontMatterTOML = "toml"
+	frontMatterJSON = "json"
+)
+
+/* frontMatterEncoder serialises front matter values including the format delimiters (e.g. `---`) */
+type frontMatterEncoder interface {
+	encode(values map[string]interface{}) (string, error)
+}
+
+func newFrontMatterEncoder(format string) (frontMatterEncoder, error) {
+	switch format {
+	case frontMatterYAML:
+		return yamlEncoder{}, nil
+	case frontMatterTOML:
+		return tomlEncoder{}, nil
+	case frontMatterJSON:
+		return jsonEncoder{}, nil
+	}
+	return nil, fmt.Errorf("frontMatter has to be one of %q, %q, or %q, got %q", frontMatterYAML, frontMatterTOML, frontMatterJSON, format)
+}
+
+/*
+frontMatterValues returns typed page properties (see typedPropertyValue)
+together with front matter values generated by the exporter (e.g. backlinks)
+*/
+func frontMatterValues(p parsedPage, propertyTypes map[string]string) map[string]interface{} {
+	values := make(map[string]interface{}, len(p.pc.attributes)+len(p.frontMatter))
+	for name, value := range p.pc.attributes {
+		values[name] = typedPropertyValue(name, value, propertyTypes)
+	}
+	for name, value := range p.frontMatter {
+		values[name] = value
+	}
+	return values
+}
+
+/*
+dateValue is a date without time (`2023-07-29`)
+YAML renders it as a timestamp and TOML as a local date, so the generators parse it as a date. JSON doesn't have dates, it renders a string.
+*/
+type dateValue string
+
+func (d dateValue) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: string(d)}, nil
+}
+
+// MarshalTOML renders the date without quotes, the toml encoder would quote the MarshalText value
+func (d dateValue) MarshalTOML() ([]byte, error) {
+	return []byte(d), nil
+}
+
+func (d dateValue) MarshalText() ([]byte, error) {
+	return []byte(d), nil
+}
+
+/*
+yamlEncoder renders strings in double quotes and lists in the flow style
+
+	title: "Hello: World"
+	tags: [tag1, tag2]
+*/
+type yamlEncoder struct{}
+
+func (yamlEncoder) encode(values map[string]interface{}) (string, error) {
+	if len(values) == 0 {
+		return "---\n---\n", nil
+	}
+	var node yaml.Node
+	if err := node.Encode(values); err != nil {
+		return "", fmt.Errorf("encoding YAML front matter failed: %w", err)
+	}
+	// node.Content contains keys and values, we only change the style of values
+	for i := 1; i < len(node.Content); i += 2 {
+		value := node.Content[i]
+		switch value.Kind {
+		case yaml.ScalarNode:
+			if value.Tag == "!!str" {
+				value.Style = yaml.DoubleQuotedStyle
+			}
+		case yaml.SequenceNode, yaml.MappingNode:
+			value.Style = yaml.FlowStyle
+		}
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return "", fmt.Errorf("encoding YAML front matter failed: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("encoding YAML front matter failed: %w", err)
+	}
+	return fmt.Sprintf("---\n%s---\n", buf.String()), nil
+}
+
+/* tomlEncoder renders the front matter between `+++` delimiters, nested maps become tables */
+type tomlEncoder struct{}
+
+func (tomlEncoder) encode(values map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(values); err != nil {
+		return "", fmt.Errorf("encoding TOML front matter failed: %w", err)
+	}
+	return fmt.Sprintf("+++\n%s+++\n", buf.String()), nil
+}
+
+/* jsonEncoder renders the front matter as a JSON object (Hugo supports JSON front matter without delimiters) */
+type jsonEncoder struct{}
+
+func (jsonEncoder) encode(values map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(values); err != nil {
+		return "", fmt.Errorf("encoding JSON front matter failed: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// render returns the page with the front matter
+func render(encoder frontMatterEncoder, values map[string]interface{}, content string) (string, error) {
+	frontMatter, err := encoder.encode(values)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{frontMatter, content}, ""), nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrontMatterValues(t *testing.T) {
+	page := parsedPage{
+		pc: parsedContent{attributes: map[string]string{
+			"tags":   "tag1, [[another tag]]",
+			"title":  "1984",
+			"public": "true",
+			"rating": "4",
+		}},
+		frontMatter: map[string]interface{}{"aliases": []interface{}{"/logseq-pages/alias"}},
+	}
+
+	result := frontMatterValues(page, map[string]string{"rating": propertyTypeString})
+
+	require.Equal(t, map[string]interface{}{
+		"tags":    []interface{}{"tag1", "another tag"},
+		"title":   "1984",
+		"public":  true,
+		"rating":  "4",
+		"aliases": []interface{}{"/logseq-pages/alias"},
+	}, result)
+}
+
+func TestRender(t *testing.T) {
+	t.Run("it renders string values as quoted strings", func(t *testing.T) {
+		result, err := render(yamlEncoder{}, map[string]interface{}{"first": "1", "second": 2}, "page text")
+		require.NoError(t, err)
+		require.Equal(t, "---\nfirst: \"1\"\nsecond: 2\n---\npage text", result)
+	})
+
+	t.Run("it renders attributes in alphabetical order", func(t *testing.T) {
+		result, err := render(yamlEncoder{}, map[string]interface{}{"e": 1, "d": 1, "c": 1, "b": 1, "a": 1}, "page text")
+		require.NoError(t, err)
+		require.Equal(t, "---\na: 1\nb: 1\nc: 1\nd: 1\ne: 1\n---\npage text", result)
+	})
+}
+
+func TestFrontMatterEncoders(t *testing.T) {
+	values := map[string]interface{}{
+		"title": "Quotes \" and: colons\nsecond line, ünicode",
+		"tags":  []interface{}{"tag1", "multi word"},
+		"date":  dateValue("2023-07-29"),
+		"extra": map[string]interface{}{"public": true},
+	}
+
+	t.Run("yaml", func(t *testing.T) {
+		result, err := yamlEncoder{}.encode(values)
+		require.NoError(t, err)
+		require.Equal(t, "---\ndate: 2023-07-29\nextra: {public: true}\ntags: [tag1, multi word]\ntitle: \"Quotes \\\" and: colons\\nsecond line, ünicode\"\n---\n", result)
+	})
+
+	t.Run("toml", func(t *testing.T) {
+		result, err := tomlEncoder{}.encode(values)
+		require.NoError(t, err)
+		require.Equal(t, "+++\ndate = 2023-07-29\ntags = [\"tag1\", \"multi word\"]\ntitle = \"Quotes \\\" and: colons\\nsecond line, ünicode\"\n\n[extra]\n  public = true\n+++\n", result)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		result, err := jsonEncoder{}.encode(values)
+		require.NoError(t, err)
+		require.Equal(t, "{\n  \"date\": \"2023-07-29\",\n  \"extra\": {\n    \"public\": true\n  },\n  \"tags\": [\n    \"tag1\",\n    \"multi word\"\n  ],\n  \"title\": \"Quotes \\\" and: colons\\nsecond line, ünicode\"\n}\n", result)
+	})
+
+	t.Run("doesn't wrap long strings", func(t *testing.T) {
+		title := "a very long title that would normally be wrapped by the YAML encoder because it is longer than eighty characters"
+		result, err := yamlEncoder{}.encode(map[string]interface{}{"title": title})
+		require.NoError(t, err)
+		require.Equal(t, "---\ntitle: \""+title+"\"\n---\n", result)
+	})
+}
@@ -3,24 +3,26 @@ module github.com/viktomas/logseq-export
 go 1.19
 
 require (
-	github.com/spf13/afero v1.9.2
-	github.com/stretchr/testify v1.8.1
-	golang.org/x/exp v0.0.0-20220921164117-439092de6870
-	github.com/knadh/koanf/v2 v2.0.1
+	github.com/BurntSushi/toml v1.3.2
+	github.com/fsnotify/fsnotify v1.6.0
 	github.com/knadh/koanf/parsers/yaml v0.1.0
 	github.com/knadh/koanf/providers/basicflag v0.1.0
 	github.com/knadh/koanf/providers/file v0.1.0
+	github.com/knadh/koanf/v2 v2.0.1
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/spf13/afero v1.9.2
+	github.com/stretchr/testify v1.8.1
+	golang.org/x/exp v0.0.0-20220921164117-439092de6870
+	golang.org/x/image v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/fsnotify/fsnotify v1.6.0 // indirect
 	github.com/knadh/koanf/maps v0.1.1 // indirect
 	github.com/mitchellh/copystructure v1.2.0 // indirect
 	github.com/mitchellh/mapstructure v1.5.0 // indirect
 	github.com/mitchellh/reflectwalk v1.0.2 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/sys v0.10.0 // indirect
-	golang.org/x/text v0.3.4 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"html"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/image/draw"
+)
+
+/*
+imageOptions configure the processing of exported JPEG and PNG images
+Processed images are decoded and encoded again, which removes all metadata (EXIF, GPS).
+*/
+type imageOptions struct {
+	// maxWidth and maxHeight limit the size of the exported image, 0 means no limit
+	maxWidth  int
+	maxHeight int
+	// quality is the JPEG quality (1-100)
+	quality int
+	// widths are the widths of the responsive variants of the image
+	widths []int
+}
+
+// newImageOptions returns nil if the image processing is turned off
+func newImageOptions(config *Config) *imageOptions {
+	if !config.ProcessImages {
+		return nil
+	}
+	return &imageOptions{
+		maxWidth:  config.ImageMaxWidth,
+		maxHeight: config.ImageMaxHeight,
+		quality:   config.ImageQuality,
+		widths:    config.ImageWidths,
+	}
+}
+
+// isProcessedImage returns true for images that the image processing supports
+func isProcessedImage(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	}
+	return false
+}
+
+// imageVariant is a smaller version of the exported image used in the `srcset`
+type imageVariant struct {
+	// dest is relative to the output folder
+	dest  string
+	url   string
+	width int
+}
+
+// variantName adds the width to the file name (`image.jpg` -> `image-480w.jpg`)
+func variantName(name string, width int) string {
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s-%dw%s", strings.TrimSuffix(name, ext), width, ext)
+}
+
+/*
+fitSize returns the size of the image that fits into maxWidth and maxHeight, images are never enlarged
+The width (if it's not 0) is the requested width of a variant.
+*/
+func fitSize(width, height int, opts imageOptions, requestedWidth int) (int, int) {
+	scale := 1.0
+	if opts.maxWidth > 0 && width > opts.maxWidth {
+		scale = float64(opts.maxWidth) / float64(width)
+	}
+	if opts.maxHeight > 0 && float64(height)*scale > float64(opts.maxHeight) {
+		scale = float64(opts.maxHeight) / float64(height)
+	}
+	if requestedWidth > 0 && float64(requestedWidth) < float64(width)*scale {
+		scale = float64(requestedWidth) / float64(width)
+	}
+	w, h := int(float64(width)*scale+0.5), int(float64(height)*scale+0.5)
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	return w, h
+}
+
+// imageWidth returns the width of the exported image (after applying the EXIF orientation and maximum size)
+func imageWidth(content []byte, opts imageOptions) (int, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return 0, err
+	}
+	width, height := config.Width, config.Height
+	if jpegOrientation(content) >= 5 {
+		width, height = height, width
+	}
+	w, _ := fitSize(width, height, opts, 0)
+	return w, nil
+}
+
+// variantWidths returns the configured widths that are smaller than the image, sorted from the smallest
+func variantWidths(imageWidth int, opts imageOptions) []int {
+	var widths []int
+	for _, w := range opts.widths {
+		if w > 0 && w < imageWidth && !slices.Contains(widths, w) {
+			widths = append(widths, w)
+		}
+	}
+	slices.Sort(widths)
+	return widths
+}
+
+/*
+processImage resizes the image and encodes it again in the same format
+The width (if it's not 0) is the width of a variant. JPEG images are rotated based on their EXIF orientation
+because the orientation is removed together with the other metadata.
+*/
+func processImage(content []byte, opts imageOptions, width int) ([]byte, error) {
+	img, format, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("decoding image failed: %w", err)
+	}
+	if format == "jpeg" {
+		img = orientImage(img, jpegOrientation(content))
+	}
+	bounds := img.Bounds()
+	w, h := fitSize(bounds.Dx(), bounds.Dy(), opts, width)
+	if w != bounds.Dx() || h != bounds.Dy() {
+		resized := image.NewNRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(resized, resized.Bounds(), img, bounds, draw.Src, nil)
+		img = resized
+	}
+
+	var out bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&out, img, &jpeg.Options{Quality: opts.quality})
+	case "png":
+		err = png.Encode(&out, img)
+	default:
+		err = fmt.Errorf("unsupported image format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encoding image failed: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+/*
+jpegOrientation returns the EXIF orientation (1-8) of the JPEG image
+It returns 1 (no transformation) if the image doesn't have one.
+*/
+func jpegOrientation(content []byte) int {
+	if len(content) < 4 || content[0] != 0xFF || content[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(content) && content[i] == 0xFF; {
+		marker := content[i+1]
+		length := int(binary.BigEndian.Uint16(content[i+2 : i+4]))
+		// the image data starts after the start of scan marker, there is no EXIF after it
+		// the length includes its own two bytes, shorter segments are corrupt
+		if marker == 0xDA || length < 2 || i+2+length > len(content) {
+			return 1
+		}
+		segment := content[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation reads the orientation tag from the first IFD of the EXIF (TIFF) data
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset : offset+2]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// orientImage transforms the image so that it looks the same as with the EXIF orientation
+func orientImage(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	src := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirrored along the top-right diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			d, s := dst.PixOffset(dx, dy), src.PixOffset(x, y)
+			dst.Pix[d], dst.Pix[d+1], dst.Pix[d+2], dst.Pix[d+3] = src.Pix[s], src.Pix[s+1], src.Pix[s+2], src.Pix[s+3]
+		}
+	}
+	return dst
+}
+
+/* imageSize is the size set in Logseq by resizing the image, 0 means that the dimension isn't set */
+type imageSize struct {
+	width  int
+	height int
+}
+
+func (s imageSize) isSet() bool {
+	return s.width > 0 || s.height > 0
+}
+
+var imageSizeAttributeRegexp = regexp.MustCompile(`:(width|height)\s+(\d+)`)
+
+// parseImageSize parses Logseq image attributes `{:height 300, :width 400}`
+func parseImageSize(attributes string) imageSize {
+	var size imageSize
+	for _, m := range imageSizeAttributeRegexp.FindAllStringSubmatch(attributes, -1) {
+		value, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+		if m[1] == "width" {
+			size.width = value
+		} else {
+			size.height = value
+		}
+	}
+	return size
+}
+
+// htmlImage renders an HTML image, srcset and the size are optional
+func htmlImage(alt, url, srcset string, size imageSize) string {
+	attributes := fmt.Sprintf(`src="%s"`, html.EscapeString(url))
+	if srcset != "" {
+		attributes += fmt.Sprintf(` srcset="%s"`, html.EscapeString(srcset))
+	}
+	attributes += fmt.Sprintf(` alt="%s"`, html.EscapeString(alt))
+	if size.width > 0 {
+		attributes += fmt.Sprintf(` width="%d"`, size.width)
+	}
+	if size.height > 0 {
+		attributes += fmt.Sprintf(` height="%d"`, size.height)
+	}
+	return "<img " + attributes + ">"
+}
+
+// renderResponsiveImage renders the image of the target with the `srcset` of all variants
+func renderResponsiveImage(t target, alt string, asset exportedAsset, size imageSize) string {
+	srcset := make([]string, 0, len(asset.variants)+1)
+	for _, v := range asset.variants {
+		srcset = append(srcset, fmt.Sprintf("%s %dw", v.url, v.width))
+	}
+	srcset = append(srcset, fmt.Sprintf("%s %dw", asset.url, asset.width))
+	return t.image(alt, asset.url, strings.Join(srcset, ", "), size)
+}
+
+var sizedImageRegexp = regexp.MustCompile(`!\[([^\]\n]*)]\(([^)\s]+)\)\{(:[^}\n]*)}`)
+
+/*
+replaceImageSizes converts images with Logseq attributes (`![alt](/image.png){:height 300, :width 400}`)
+to sized images of the target. Attributes without a size are removed.
+*/
+func replaceImageSizes(content string, t target) string {
+	return sizedImageRegexp.ReplaceAllStringFunc(content, func(match string) string {
+		submatches := sizedImageRegexp.FindStringSubmatch(match)
+		alt, url := submatches[1], submatches[2]
+		size := parseImageSize(submatches[3])
+		if !size.isSet() {
+			return fmt.Sprintf("![%s](%s)", alt, url)
+		}
+		return t.image(alt, url, "", size)
+	})
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+// testImage returns a width x height image with red top left pixel
+func testImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{0, 0, 255, 255})
+		}
+	}
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	require.NoError(t, png.Encode(&out, img))
+	return out.Bytes()
+}
+
+// encodeJPEG encodes the image with an EXIF segment containing the orientation
+func encodeJPEG(t *testing.T, img image.Image, orientation uint16) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	require.NoError(t, jpeg.Encode(&out, img, &jpeg.Options{Quality: 100}))
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+	return append(append([]byte{0xFF, 0xD8}, app1...), out.Bytes()[2:]...)
+}
+
+func TestFitSize(t *testing.T) {
+	opts := imageOptions{maxWidth: 1000, maxHeight: 500}
+	testCases := []struct {
+		desc           string
+		width, height  int
+		requestedWidth int
+		expectedWidth  int
+		expectedHeight int
+	}{
+		{desc: "small image keeps its size", width: 200, height: 100, expectedWidth: 200, expectedHeight: 100},
+		{desc: "wide image fits max width", width: 2000, height: 500, expectedWidth: 1000, expectedHeight: 250},
+		{desc: "tall image fits max height", width: 1000, height: 1000, expectedWidth: 500, expectedHeight: 500},
+		{desc: "variant has requested width", width: 2000, height: 500, requestedWidth: 400, expectedWidth: 400, expectedHeight: 100},
+		{desc: "variant isn't enlarged", width: 200, height: 100, requestedWidth: 400, expectedWidth: 200, expectedHeight: 100},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			w, h := fitSize(tC.width, tC.height, opts, tC.requestedWidth)
+			require.Equal(t, tC.expectedWidth, w)
+			require.Equal(t, tC.expectedHeight, h)
+		})
+	}
+}
+
+func TestVariantWidths(t *testing.T) {
+	require.Equal(t, []int{320, 640}, variantWidths(1000, imageOptions{widths: []int{640, 1200, 320, 640, 1000}}))
+	require.Empty(t, variantWidths(200, imageOptions{widths: []int{320}}))
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	require.Equal(t, 6, jpegOrientation(encodeJPEG(t, testImage(4, 2), 6)))
+	require.Equal(t, 1, jpegOrientation(encodePNG(t, testImage(4, 2))))
+	require.Equal(t, 1, jpegOrientation([]byte("not an image")))
+	// APP1 segment with length 1 that would end before it starts
+	require.Equal(t, 1, jpegOrientation([]byte("\xFF\xD8\xFF\xE1\x00\x01Exif\x00\x00")))
+}
+
+func TestOrientImage(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	testCases := []struct {
+		orientation int
+		// position of the top left pixel after the transformation
+		x, y int
+	}{
+		{orientation: 2, x: 3, y: 0},
+		{orientation: 3, x: 3, y: 1},
+		{orientation: 4, x: 0, y: 1},
+		{orientation: 5, x: 0, y: 0},
+		{orientation: 6, x: 1, y: 0},
+		{orientation: 7, x: 1, y: 3},
+		{orientation: 8, x: 0, y: 3},
+	}
+	for _, tC := range testCases {
+		oriented := orientImage(testImage(4, 2), tC.orientation)
+		if tC.orientation >= 5 {
+			require.Equal(t, image.Rect(0, 0, 2, 4), oriented.Bounds(), "orientation %d", tC.orientation)
+		}
+		require.Equal(t, red, oriented.At(tC.x, tC.y), "orientation %d", tC.orientation)
+	}
+}
+
+func TestProcessImage(t *testing.T) {
+	opts := imageOptions{maxWidth: 100, quality: 80}
+
+	t.Run("resizes PNG", func(t *testing.T) {
+		processed, err := processImage(encodePNG(t, testImage(400, 200)), opts, 0)
+		require.NoError(t, err)
+		config, format, err := image.DecodeConfig(bytes.NewReader(processed))
+		require.NoError(t, err)
+		require.Equal(t, "png", format)
+		require.Equal(t, 100, config.Width)
+		require.Equal(t, 50, config.Height)
+	})
+
+	t.Run("rotates JPEG and removes EXIF", func(t *testing.T) {
+		processed, err := processImage(encodeJPEG(t, testImage(40, 20), 6), opts, 0)
+		require.NoError(t, err)
+		require.NotContains(t, string(processed), "Exif")
+		config, format, err := image.DecodeConfig(bytes.NewReader(processed))
+		require.NoError(t, err)
+		require.Equal(t, "jpeg", format)
+		require.Equal(t, 20, config.Width)
+		require.Equal(t, 40, config.Height)
+	})
+
+	t.Run("creates variant", func(t *testing.T) {
+		processed, err := processImage(encodePNG(t, testImage(400, 200)), opts, 40)
+		require.NoError(t, err)
+		config, _, err := image.DecodeConfig(bytes.NewReader(processed))
+		require.NoError(t, err)
+		require.Equal(t, 40, config.Width)
+	})
+}
+
+func TestExportProcessedImages(t *testing.T) {
+	appFS := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(appFS, "/graph/assets/photo.jpg", encodeJPEG(t, testImage(400, 200), 1), 0644))
+	config := testConfig()
+	config.LogseqFolder = "/graph"
+	config.ProcessImages = true
+	config.ImageMaxWidth = 200
+	config.ImageWidths = []int{100, 300}
+	page := parsedPage{
+		originalPath: "/graph/pages/a.md",
+		pc: parsedContent{
+			content: "![photo](../assets/photo.jpg)",
+			assets:  []string{"../assets/photo.jpg"},
+		},
+	}
+
+	assets := collectAssets(appFS, testTarget(), config, []parsedPage{page})
+	writer, err := newOutputWriter(appFS, "/out", config)
+	require.NoError(t, err)
+	exportAssets(writer, assets)
+
+	require.Equal(t, `{{< logseq-image src="/logseq-assets/photo.jpg" srcset="/logseq-assets/photo-100w.jpg 100w, /logseq-assets/photo.jpg 200w" alt="photo" >}}`, replaceAssetPaths(page, assets, testTarget(), config))
+	for path, width := range map[string]int{"/out/logseq-assets/photo.jpg": 200, "/out/logseq-assets/photo-100w.jpg": 100} {
+		content, err := afero.ReadFile(appFS, path)
+		require.NoError(t, err)
+		imageConfig, _, err := image.DecodeConfig(bytes.NewReader(content))
+		require.NoError(t, err)
+		require.Equal(t, width, imageConfig.Width, path)
+	}
+}
+
+func TestParseImageSize(t *testing.T) {
+	require.Equal(t, imageSize{width: 400, height: 300}, parseImageSize("{:height 300, :width 400}"))
+	require.Equal(t, imageSize{width: 400}, parseImageSize("{:width 400}"))
+	require.False(t, parseImageSize("{:class big}").isSet())
+}
+
+func TestReplaceImageSizes(t *testing.T) {
+	t.Run("renders sized image for the target", func(t *testing.T) {
+		content := "![img](/logseq-assets/a.png){:height 300, :width 400}\n![remote](https://example.com/b.png){:width 200}"
+		require.Equal(
+			t,
+			"{{< figure src=\"/logseq-assets/a.png\" alt=\"img\" width=\"400\" height=\"300\" >}}\n{{< figure src=\"https://example.com/b.png\" alt=\"remote\" width=\"200\" >}}",
+			replaceImageSizes(content, testTarget()),
+		)
+	})
+
+	t.Run("removes attributes without size", func(t *testing.T) {
+		require.Equal(t, "![img](/a.png)", replaceImageSizes("![img](/a.png){:class big}", testTarget()))
+	})
+
+	t.Run("keeps images without attributes", func(t *testing.T) {
+		require.Equal(t, "![img](/a.png) {not attributes}", replaceImageSizes("![img](/a.png) {not attributes}", testTarget()))
+	})
+
+	t.Run("adds size to responsive images", func(t *testing.T) {
+		asset := exportedAsset{url: "/a.png", width: 800, variants: []imageVariant{{url: "/a-400w.png", width: 400}}}
+		require.Equal(t, `{{< logseq-image src="/a.png" srcset="/a-400w.png 400w, /a.png 800w" alt="img" width="400" >}}`, renderResponsiveImage(testTarget(), "img", asset, imageSize{width: 400}))
+	})
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+/*
+previousPage is a page recorded in the manifest of the previous export
+The export reuses it if the page inputs (its source and sources of the pages it embeds or references)
+didn't change, so that the page doesn't have to be parsed.
+*/
+type previousPage struct {
+	// path is the output path relative to the output folder (with forward slashes)
+	path  string
+	entry pageEntry
+}
+
+/*
+previousPages returns pages recorded by the previous export by their source path
+Nothing is reused with --clean or after the export configuration changed.
+*/
+func previousPages(appFS afero.Fs, config *Config) (map[string]previousPage, error) {
+	if config.Clean {
+		return nil, nil
+	}
+	m, err := loadManifest(appFS, config.OutputFolder)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := configHash(config)
+	if err != nil {
+		return nil, err
+	}
+	if m.ConfigHash != hash {
+		return nil, nil
+	}
+	pages := make(map[string]previousPage, len(m.Pages))
+	for key, entry := range m.Pages {
+		pages[entry.Source] = previousPage{path: key, entry: entry}
+	}
+	return pages, nil
+}
+
+// restore returns the parts of the page that other pages need (title, slug, aliases, links, and assets)
+func (p previousPage) restore() parsedPage {
+	attributes := map[string]string{
+		"title": p.entry.Title,
+		"slug":  p.entry.Slug,
+	}
+	if p.entry.Alias != "" {
+		attributes["alias"] = p.entry.Alias
+	}
+	return parsedPage{
+		originalPath: p.entry.Source,
+		pc: parsedContent{
+			attributes: attributes,
+			assets:     p.entry.Assets,
+			links:      p.entry.Links,
+		},
+		frontMatter: map[string]interface{}{},
+	}
+}
+
+/*
+pageDependencies returns all embeds and block references that the page content depends on
+(including the ones in the embedded and referenced pages) mapped to the source path of the page that contains them.
+Embeds and references that don't resolve map to an empty string, the page depends on them appearing later.
+*/
+func pageDependencies(page textFile, pageIndex map[string]textFile, blockIndex map[string]indexedBlock) map[string]string {
+	dependencies := map[string]string{}
+	var scan func(content string)
+	add := func(key string, dependency textFile, ok bool) {
+		if _, seen := dependencies[key]; seen {
+			return
+		}
+		if !ok {
+			dependencies[key] = ""
+			return
+		}
+		dependencies[key] = dependency.absoluteFSPath
+		scan(dependency.content)
+	}
+	scan = func(content string) {
+		for _, match := range embedRegexp.FindAllStringSubmatch(content, -1) {
+			if pageName := match[1]; pageName != "" {
+				dependency, ok := pageIndex[normalizePageName(pageName)]
+				add(pageEmbedKey(pageName), dependency, ok)
+				continue
+			}
+			b, ok := blockIndex[strings.ToLower(match[2])]
+			add(blockEmbedKey(match[2]), b.page, ok)
+		}
+		for _, match := range blockRefRegexp.FindAllStringSubmatch(content, -1) {
+			b, ok := blockIndex[strings.ToLower(match[1])]
+			add(blockEmbedKey(match[1]), b.page, ok)
+		}
+	}
+	scan(page.content)
+	return dependencies
+}
+
+// sourceHashes returns the hash of every page source by its path
+func sourceHashes(pages []textFile) map[string]string {
+	hashes := make(map[string]string, len(pages))
+	for _, page := range pages {
+		hashes[page.absoluteFSPath] = hashBytes([]byte(page.content))
+	}
+	return hashes
+}
+
+// inputHash changes every time the page source, the graph config, or the source of any of its dependencies changes
+func inputHash(page textFile, dependencies map[string]string, hashes map[string]string, graphConfig graphConfig) string {
+	keys := make([]string, 0, len(dependencies))
+	for key := range dependencies {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q %q\n%s %s\n", graphConfig.journalFileNameFormat, graphConfig.journalPageTitleFormat, page.absoluteFSPath, hashes[page.absoluteFSPath])
+	for _, key := range keys {
+		path := dependencies[key]
+		fmt.Fprintf(&b, "%s %s %s\n", key, path, hashes[path])
+	}
+	return hashBytes([]byte(b.String()))
+}
+
+/*
+contextHash changes every time something that the page output uses from other pages and assets changes:
+slugs of the linked pages and tags (or the linked page not being published anymore), the backlinks, and the linked assets
+*/
+func contextHash(p parsedPage, tags []string, titleToSlug map[string]string, backlinks []backlink, assets map[string]exportedAsset) string {
+	var b strings.Builder
+	for _, name := range append(append([]string{}, p.pc.links...), tags...) {
+		slug, ok := titleToSlug[normalizePageName(name)]
+		fmt.Fprintf(&b, "link %q %t %q\n", name, ok, slug)
+	}
+	for _, l := range backlinks {
+		fmt.Fprintf(&b, "backlink %q %q\n", l.title, l.url)
+	}
+	for _, link := range p.pc.assets {
+		asset := assets[assetSourcePath(p, link)]
+		fmt.Fprintf(&b, "asset %q %q %t %d", link, asset.url, asset.missing, asset.width)
+		for _, v := range asset.variants {
+			fmt.Fprintf(&b, " %q %d", v.url, v.width)
+		}
+		b.WriteString("\n")
+	}
+	return hashBytes([]byte(b.String()))
+}
+
+/* pageExport is a public page on its way through the export */
+type pageExport struct {
+	source textFile
+	page   parsedPage
+	// previous is set if the page inputs didn't change since the previous export, the page is restored from the manifest
+	previous  *previousPage
+	inputHash string
+	// parsed is false until a restored page has to be rendered
+	parsed      bool
+	contextHash string
+	// kept is true if the output from the previous export is up to date, content is empty in that case
+	kept     bool
+	content  string
+	dangling []string
+}
+
+// entry returns the manifest entry for the rendered page
+func (e pageExport) entry() pageEntry {
+	return pageEntry{
+		manifestEntry: manifestEntry{Source: e.source.absoluteFSPath},
+		InputHash:     e.inputHash,
+		ContextHash:   e.contextHash,
+		Title:         e.page.pc.attributes["title"],
+		Slug:          e.page.pc.attributes["slug"],
+		Alias:         e.page.pc.attributes["alias"],
+		Links:         e.page.pc.links,
+		Tags:          detectTags(e.page.pc.content),
+		Assets:        e.page.pc.assets,
+		Dangling:      e.dangling,
+	}
+}
+
+// outputUnchanged returns true if the file in the output folder is the one that the previous export wrote
+func outputUnchanged(appFS afero.Fs, outputFolder string, p previousPage) bool {
+	return fileHash(appFS, filepath.Join(outputFolder, filepath.FromSlash(p.path))) == p.entry.OutputHash
+}
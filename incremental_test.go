@@ -0,0 +1,156 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageDependencies(t *testing.T) {
+	pages := []textFile{
+		{absoluteFSPath: "/graph/pages/a.md", content: "public:: true\n\n- {{embed [[Bee]]}}\n- see ((64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f))\n- {{embed [[missing]]}}"},
+		{absoluteFSPath: "/graph/pages/b.md", content: "alias:: bee\n\n- {{embed ((64c4f1a2-1111-4a3f-9a3c-7a9b2d7c1e3f))}}"},
+		{absoluteFSPath: "/graph/pages/c.md", content: "- referenced\n  id:: 64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f"},
+		{absoluteFSPath: "/graph/pages/d.md", content: "- embedded [[Link]]\n  id:: 64c4f1a2-1111-4a3f-9a3c-7a9b2d7c1e3f"},
+		{absoluteFSPath: "/graph/pages/link.md", content: "- only linked"},
+	}
+	pageIndex := buildPageIndex(pages)
+	blockIndex := buildBlockIndex(pages, testConfig(), newPrivateBlockMatcher(testConfig()))
+
+	result := pageDependencies(pages[0], pageIndex, blockIndex)
+
+	require.Equal(t, map[string]string{
+		pageEmbedKey("Bee"): "/graph/pages/b.md",
+		blockEmbedKey("64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f"): "/graph/pages/c.md",
+		blockEmbedKey("64c4f1a2-1111-4a3f-9a3c-7a9b2d7c1e3f"): "/graph/pages/d.md",
+		pageEmbedKey("missing"):                               "",
+	}, result, "links are not dependencies, the page uses only the slug of the linked page")
+}
+
+func TestRenderPages(t *testing.T) {
+	setup := func(t *testing.T) (afero.Fs, *Config) {
+		appFS := afero.NewMemMapFs()
+		for name, content := range map[string]string{
+			"a.md":       "public:: true\n\n- embeds {{embed [[B]]}}",
+			"b.md":       "public:: true\n\n- text of b",
+			"c.md":       "public:: true\n\n- links to [[B]]",
+			"d.md":       "public:: true\n\n- unrelated",
+			"e.md":       "public:: true\n\n- refers to ((64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f))",
+			"private.md": "- private block\n  id:: 64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f",
+		} {
+			require.NoError(t, afero.WriteFile(appFS, filepath.Join("/graph/pages", name), []byte(content), 0644))
+		}
+		config := testConfig()
+		config.LogseqFolder = "/graph"
+		config.OutputFolder = "/out"
+		config.PrivateBlockRefs = privateBlockRefsInline
+		_, err := exportGraph(appFS, config)
+		require.NoError(t, err)
+		return appFS, config
+	}
+	writePage := func(t *testing.T, appFS afero.Fs, name, content string) {
+		require.NoError(t, afero.WriteFile(appFS, filepath.Join("/graph/pages", name), []byte(content), 0644))
+	}
+	renderedPages := func(t *testing.T, appFS afero.Fs, config *Config) []string {
+		pages, err := loadPages(appFS, config.LogseqFolder, testGraphConfig)
+		require.NoError(t, err)
+		target, err := newTarget(config)
+		require.NoError(t, err)
+		exports, _, err := renderPages(appFS, config, target, testGraphConfig, pages)
+		require.NoError(t, err)
+		var rendered []string
+		for _, e := range exports {
+			if !e.kept {
+				rendered = append(rendered, filepath.Base(e.source.absoluteFSPath))
+			}
+		}
+		return rendered
+	}
+	readOutput := func(t *testing.T, appFS afero.Fs, name string) string {
+		m, err := loadManifest(appFS, "/out")
+		require.NoError(t, err)
+		for key, entry := range m.Pages {
+			if entry.Source == filepath.Join("/graph/pages", name) {
+				content, err := afero.ReadFile(appFS, filepath.Join("/out", key))
+				require.NoError(t, err)
+				return string(content)
+			}
+		}
+		t.Fatalf("page %q was not exported", name)
+		return ""
+	}
+
+	t.Run("doesn't render pages that didn't change", func(t *testing.T) {
+		appFS, config := setup(t)
+		require.Empty(t, renderedPages(t, appFS, config))
+	})
+
+	t.Run("renders pages that embed the changed page", func(t *testing.T) {
+		appFS, config := setup(t)
+		writePage(t, appFS, "b.md", "public:: true\n\n- new text of b")
+		require.Equal(t, []string{"a.md", "b.md"}, renderedPages(t, appFS, config))
+
+		_, err := exportGraph(appFS, config)
+		require.NoError(t, err)
+		require.Contains(t, readOutput(t, appFS, "a.md"), "new text of b")
+	})
+
+	t.Run("renders pages that reference a block from the changed page", func(t *testing.T) {
+		appFS, config := setup(t)
+		writePage(t, appFS, "private.md", "- changed private block\n  id:: 64c4f1a2-0000-4a3f-9a3c-7a9b2d7c1e3f")
+		require.Equal(t, []string{"e.md"}, renderedPages(t, appFS, config))
+	})
+
+	t.Run("renders pages whose backlinks changed", func(t *testing.T) {
+		appFS, config := setup(t)
+		writePage(t, appFS, "d.md", "public:: true\n\n- now links to [[B]]")
+		require.Equal(t, []string{"b.md", "d.md"}, renderedPages(t, appFS, config))
+	})
+
+	t.Run("renders pages that link to a renamed page", func(t *testing.T) {
+		appFS, config := setup(t)
+		writePage(t, appFS, "b.md", "public:: true\nslug:: bee\n\n- text of b")
+		require.Equal(t, []string{"a.md", "b.md", "c.md"}, renderedPages(t, appFS, config))
+
+		_, err := exportGraph(appFS, config)
+		require.NoError(t, err)
+		require.Contains(t, readOutput(t, appFS, "c.md"), "[B](/logseq-pages/bee)")
+	})
+
+	t.Run("renders pages that link to a new alias", func(t *testing.T) {
+		appFS, config := setup(t)
+		writePage(t, appFS, "d.md", "public:: true\n\n- links to [[Bee]]")
+		writePage(t, appFS, "b.md", "public:: true\nalias:: Bee\n\n- text of b")
+		require.Equal(t, []string{"a.md", "b.md", "d.md"}, renderedPages(t, appFS, config))
+	})
+
+	t.Run("renders pages whose output was edited", func(t *testing.T) {
+		appFS, config := setup(t)
+		m, err := loadManifest(appFS, "/out")
+		require.NoError(t, err)
+		for key, entry := range m.Pages {
+			if filepath.Base(entry.Source) == "d.md" {
+				require.NoError(t, afero.WriteFile(appFS, filepath.Join("/out", key), []byte("edited"), 0644))
+			}
+		}
+		require.Equal(t, []string{"d.md"}, renderedPages(t, appFS, config))
+
+		_, err = exportGraph(appFS, config)
+		require.NoError(t, err)
+		require.Contains(t, readOutput(t, appFS, "d.md"), "unrelated")
+	})
+
+	t.Run("renders all pages after the configuration changed", func(t *testing.T) {
+		appFS, config := setup(t)
+		config.UnpublishedLinks = unpublishedLinksText
+		require.Len(t, renderedPages(t, appFS, config), 5)
+	})
+
+	t.Run("renders all pages with --clean", func(t *testing.T) {
+		appFS, config := setup(t)
+		config.Clean = true
+		require.Len(t, renderedPages(t, appFS, config), 5)
+	})
+}
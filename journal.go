@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const defaultJournalFileNameFormat = "yyyy_MM_dd"
+const defaultJournalPageTitleFormat = "MMM do, yyyy"
+
+// journalDateLayout is the format of the date attribute that we generate for journal pages
+const journalDateLayout = "2006-01-02"
+
+/* graphConfig captures the settings from logseq/config.edn that influence the export */
+type graphConfig struct {
+	journalFileNameFormat  string
+	journalPageTitleFormat string
+}
+
+/* journal captures the date and title that Logseq derives from the journal file name */
+type journal struct {
+	date  time.Time
+	title string
+}
+
+/*
+loadGraphConfig reads the journal settings from logseq/config.edn
+
+The file is EDN and we only need a few string values, so we look for uncommented
+lines like `:journal/file-name-format "yyyy_MM_dd"` instead of parsing the whole file.
+*/
+func loadGraphConfig(appFS afero.Fs, logseqFolder string) (graphConfig, error) {
+	config := graphConfig{
+		journalFileNameFormat:  defaultJournalFileNameFormat,
+		journalPageTitleFormat: defaultJournalPageTitleFormat,
+	}
+	configPath := filepath.Join(logseqFolder, "logseq", "config.edn")
+	content, err := afero.ReadFile(appFS, configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("reading logseq config %q failed: %w", configPath, err)
+	}
+	if format, ok := findEdnString(string(content), ":journal/file-name-format"); ok {
+		config.journalFileNameFormat = format
+	}
+	if format, ok := findEdnString(string(content), ":journal/page-title-format"); ok {
+		config.journalPageTitleFormat = format
+	}
+	return config, nil
+}
+
+func findEdnString(content, key string) (string, bool) {
+	// lines starting with ; are comments
+	keyRegexp := regexp.MustCompile(fmt.Sprintf(`(?m)^[^;\n]*%s\s+"([^"]*)"`, regexp.QuoteMeta(key)))
+	match := keyRegexp.FindStringSubmatch(content)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+/*
+parseJournal turns journal file name (e.g. `2023_07_29.md`) into a journal date and title
+based on the formats configured in the Logseq graph
+*/
+func parseJournal(fileName string, config graphConfig) (*journal, error) {
+	layout, err := dateFormatToLayout(config.journalFileNameFormat)
+	if err != nil {
+		return nil, err
+	}
+	date, err := time.Parse(layout, filenameWithoutExt(fileName))
+	if err != nil {
+		return nil, fmt.Errorf("journal file name %q doesn't match the %q format: %w", fileName, config.journalFileNameFormat, err)
+	}
+	return &journal{
+		date:  date,
+		title: formatDate(date, config.journalPageTitleFormat),
+	}, nil
+}
+
+/*
+dateFormatToken is either a date field (e.g. `yyyy` or `do`) or a literal text
+Logseq uses date-fns format strings where literal text can be put in single quotes.
+*/
+type dateFormatToken struct {
+	value   string
+	literal bool
+}
+
+func tokenizeDateFormat(format string) []dateFormatToken {
+	var tokens []dateFormatToken
+	for i := 0; i < len(format); {
+		c := format[i]
+		if c == '\'' {
+			end := strings.IndexByte(format[i+1:], '\'')
+			if end < 0 {
+				tokens = append(tokens, dateFormatToken{value: format[i+1:], literal: true})
+				break
+			}
+			tokens = append(tokens, dateFormatToken{value: format[i+1 : i+1+end], literal: true})
+			i += end + 2
+			continue
+		}
+		if !isASCIILetter(c) {
+			tokens = append(tokens, dateFormatToken{value: string(c), literal: true})
+			i++
+			continue
+		}
+		n := 1
+		for i+n < len(format) && format[i+n] == c {
+			n++
+		}
+		// ordinal modifier (e.g. `do` -> 1st)
+		if i+n < len(format) && format[i+n] == 'o' {
+			n++
+		}
+		tokens = append(tokens, dateFormatToken{value: format[i : i+n]})
+		i += n
+	}
+	return tokens
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+var dateTokenLayouts = map[string]string{
+	"yyyy": "2006",
+	"yy":   "06",
+	"MMMM": "January",
+	"MMM":  "Jan",
+	"MM":   "01",
+	"M":    "1",
+	"dd":   "02",
+	"d":    "2",
+	"EEEE": "Monday",
+	"EEE":  "Mon",
+	"EE":   "Mon",
+	"E":    "Mon",
+}
+
+/*
+dateFormatToLayout translates Logseq date format (`yyyy_MM_dd`) to Go time layout (`2006_01_02`)
+Ordinal tokens like `do` can't be parsed and result in an error.
+*/
+func dateFormatToLayout(format string) (string, error) {
+	layout := strings.Builder{}
+	for _, t := range tokenizeDateFormat(format) {
+		if t.literal {
+			layout.WriteString(t.value)
+			continue
+		}
+		l, ok := dateTokenLayouts[t.value]
+		if !ok {
+			return "", fmt.Errorf("date format %q contains unsupported token %q", format, t.value)
+		}
+		layout.WriteString(l)
+	}
+	return layout.String(), nil
+}
+
+func formatDate(date time.Time, format string) string {
+	result := strings.Builder{}
+	for _, t := range tokenizeDateFormat(format) {
+		switch {
+		case t.literal:
+			result.WriteString(t.value)
+		case t.value == "do":
+			result.WriteString(ordinal(date.Day()))
+		case t.value == "Mo":
+			result.WriteString(ordinal(int(date.Month())))
+		default:
+			l, ok := dateTokenLayouts[t.value]
+			if !ok {
+				// unknown tokens are kept as they are
+				result.WriteString(t.value)
+				continue
+			}
+			result.WriteString(date.Format(l))
+		}
+	}
+	return result.String()
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
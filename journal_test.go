@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadGraphConfig(t *testing.T) {
+	t.Run("uses default formats when config.edn is missing", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		config, err := loadGraphConfig(appFS, "/graph")
+		require.NoError(t, err)
+		require.Equal(t, testGraphConfig, config)
+	})
+
+	t.Run("reads journal formats and ignores comments", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		afero.WriteFile(appFS, "/graph/logseq/config.edn", []byte(`{:meta/version 1
+ ;; :journal/page-title-format "EEE do, MMM yyyy"
+ :journal/file-name-format "yyyy-MM-dd"
+ :journal/page-title-format "yyyy/MM/dd"}`), 0644)
+		config, err := loadGraphConfig(appFS, "/graph")
+		require.NoError(t, err)
+		require.Equal(t, graphConfig{
+			journalFileNameFormat:  "yyyy-MM-dd",
+			journalPageTitleFormat: "yyyy/MM/dd",
+		}, config)
+	})
+}
+
+func TestParseJournal(t *testing.T) {
+	t.Run("parses default journal file name", func(t *testing.T) {
+		result, err := parseJournal("2023_07_29.md", testGraphConfig)
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2023, 7, 29, 0, 0, 0, 0, time.UTC), result.date)
+		require.Equal(t, "Jul 29th, 2023", result.title)
+	})
+
+	t.Run("uses configured formats", func(t *testing.T) {
+		result, err := parseJournal("2023-07-01.md", graphConfig{
+			journalFileNameFormat:  "yyyy-MM-dd",
+			journalPageTitleFormat: "EEEE, MMMM do 'of' yyyy",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Saturday, July 1st of 2023", result.title)
+	})
+
+	t.Run("fails for file names that don't match the format", func(t *testing.T) {
+		_, err := parseJournal("contents.md", testGraphConfig)
+		require.Error(t, err)
+	})
+}
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, "Jan 2nd, 2023", formatDate(date, "MMM do, yyyy"))
+	require.Equal(t, "Mon 2nd, Jan 2023", formatDate(date, "EEE do, MMM yyyy"))
+	require.Equal(t, "2023-01-02", formatDate(date, "yyyy-MM-dd"))
+	require.Equal(t, "02.01.23", formatDate(date, "dd.MM.yy"))
+}
+
+func TestOrdinal(t *testing.T) {
+	for n, expected := range map[int]string{1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th", 13: "13th", 21: "21st", 22: "22nd", 31: "31st"} {
+		require.Equal(t, expected, ordinal(n))
+	}
+}
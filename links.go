@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"sort"
+	"strings"
+)
+
+/*
+buildTitleToSlug maps page titles and aliases to the page slugs so that we can turn [[links]] into URLs
+Titles take precedence over aliases. If two pages claim the same alias, the first page keeps it.
+*/
+func buildTitleToSlug(pages []parsedPage) map[string]string {
+	titleToSlug := map[string]string{}
+	for _, p := range pages {
+		titleToSlug[normalizePageName(p.pc.attributes["title"])] = p.pc.attributes["slug"]
+	}
+	aliasOwners := map[string]string{}
+	for _, p := range pages {
+		title := p.pc.attributes["title"]
+		for _, alias := range parseAliases(p.pc.attributes) {
+			key := normalizePageName(alias)
+			if owner, ok := aliasOwners[key]; ok {
+				log.Printf("warning: alias %q of page %q is ignored, the page %q already uses it", alias, title, owner)
+				continue
+			}
+			if _, ok := titleToSlug[key]; ok {
+				log.Printf("warning: alias %q of page %q is ignored, there is a page with the same title", alias, title)
+				continue
+			}
+			aliasOwners[key] = title
+			titleToSlug[key] = p.pc.attributes["slug"]
+		}
+	}
+	return titleToSlug
+}
+
+/*
+normalizePageName returns the page name the way Logseq compares page names
+Page names are case-insensitive and whitespace around the namespace separator is ignored,
+so `[[Projects/Alpha]]` and `[[projects / alpha]]` link to the same page.
+*/
+func normalizePageName(name string) string {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return strings.ToLower(strings.Join(parts, "/"))
+}
+
+/*
+addAliasRedirects adds the `aliases` front matter attribute with URLs of all page aliases
+Hugo creates redirects from these URLs to the page.
+*/
+func addAliasRedirects(p *parsedPage, t target) {
+	aliases := parseAliases(p.pc.attributes)
+	if len(aliases) == 0 {
+		return
+	}
+	urls := make([]interface{}, 0, len(aliases))
+	for _, alias := range aliases {
+		urls = append(urls, t.pageURL(slugify(alias)))
+	}
+	p.frontMatter["aliases"] = urls
+}
+
+/*
+replacePageLinks turns [[links]] to exported pages into markdown links and returns the content
+together with the names of the linked pages that are not exported (dangling links).
+Dangling links are rendered based on the unpublishedLinks config.
+*/
+func replacePageLinks(content string, titleToSlug map[string]string, t target, config *Config) (string, []string) {
+	var dangling []string
+	replaced := map[string]bool{}
+	for _, l := range detectPageLinks(content) {
+		if replaced[l] {
+			continue
+		}
+		replaced[l] = true
+		link := fmt.Sprintf("[[%s]]", l)
+		slug, ok := titleToSlug[normalizePageName(l)]
+		if ok {
+			content = strings.ReplaceAll(content, link, fmt.Sprintf("[%s](%s)", l, t.pageURL(slug)))
+			continue
+		}
+		dangling = append(dangling, l)
+		switch config.UnpublishedLinks {
+		case unpublishedLinksText:
+			content = strings.ReplaceAll(content, link, l)
+		case unpublishedLinksSpan:
+			content = strings.ReplaceAll(content, link, fmt.Sprintf(`<span class="%s">%s</span>`, config.UnpublishedLinkClass, html.EscapeString(l)))
+		}
+	}
+	return content, dangling
+}
+
+/* backlink is a link from another exported page */
+type backlink struct {
+	title string
+	url   string
+}
+
+/*
+buildBacklinks finds all pages that link to each exported page
+The result maps the slug of the linked page to the (sorted by title) pages that link to it.
+*/
+func buildBacklinks(pages []parsedPage, titleToSlug map[string]string, t target) map[string][]backlink {
+	result := map[string][]backlink{}
+	for _, p := range pages {
+		source := backlink{title: p.pc.attributes["title"], url: t.pageURL(p.pc.attributes["slug"])}
+		linked := map[string]bool{}
+		for _, l := range p.pc.links {
+			slug, ok := titleToSlug[normalizePageName(l)]
+			if !ok || linked[slug] || slug == p.pc.attributes["slug"] {
+				continue
+			}
+			linked[slug] = true
+			result[slug] = append(result[slug], source)
+		}
+	}
+	for _, backlinks := range result {
+		sort.Slice(backlinks, func(i, j int) bool { return backlinks[i].title < backlinks[j].title })
+	}
+	return result
+}
+
+/*
+addBacklinks adds backlinks to the page based on the backlinks config
+either as the `backlinks` front matter attribute or as a section at the end of the content
+*/
+func addBacklinks(p *parsedPage, content string, backlinks []backlink, config *Config) string {
+	if len(backlinks) == 0 {
+		return content
+	}
+	switch config.Backlinks {
+	case backlinksFrontMatter:
+		items := make([]interface{}, 0, len(backlinks))
+		for _, b := range backlinks {
+			items = append(items, map[string]interface{}{"title": b.title, "url": b.url})
+		}
+		p.frontMatter["backlinks"] = items
+	case backlinksSection:
+		lines := []string{strings.TrimRight(content, "\n"), "", "## " + config.BacklinksHeading, ""}
+		for _, b := range backlinks {
+			lines = append(lines, fmt.Sprintf("- [%s](%s)", b.title, b.url))
+		}
+		content = strings.Join(lines, "\n") + "\n"
+	}
+	return content
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testPage(attributes map[string]string) parsedPage {
+	return parsedPage{pc: parsedContent{attributes: attributes}, frontMatter: map[string]interface{}{}}
+}
+
+func TestBuildTitleToSlug(t *testing.T) {
+	t.Run("registers titles and aliases", func(t *testing.T) {
+		result := buildTitleToSlug([]parsedPage{
+			testPage(map[string]string{"title": "A", "slug": "a", "alias": "first, [[Second Alias]]"}),
+			testPage(map[string]string{"title": "B", "slug": "b"}),
+		})
+		require.Equal(t, map[string]string{
+			"a":            "a",
+			"first":        "a",
+			"second alias": "a",
+			"b":            "b",
+		}, result)
+	})
+
+	t.Run("first page keeps a duplicate alias", func(t *testing.T) {
+		result := buildTitleToSlug([]parsedPage{
+			testPage(map[string]string{"title": "A", "slug": "a", "alias": "shared"}),
+			testPage(map[string]string{"title": "B", "slug": "b", "alias": "shared"}),
+		})
+		require.Equal(t, "a", result["shared"])
+	})
+
+	t.Run("titles take precedence over aliases", func(t *testing.T) {
+		result := buildTitleToSlug([]parsedPage{
+			testPage(map[string]string{"title": "A", "slug": "a", "alias": "B"}),
+			testPage(map[string]string{"title": "B", "slug": "b"}),
+		})
+		require.Equal(t, "b", result["b"])
+	})
+}
+
+func TestNormalizePageName(t *testing.T) {
+	require.Equal(t, "projects/alpha", normalizePageName("Projects/Alpha"))
+	require.Equal(t, "projects/alpha", normalizePageName(" projects / alpha "))
+	require.Equal(t, "hello world", normalizePageName("Hello World"))
+}
+
+func TestAddAliasRedirects(t *testing.T) {
+	t.Run("adds URLs of all aliases", func(t *testing.T) {
+		page := testPage(map[string]string{"title": "A", "alias": "First Alias, [[second]]"})
+		addAliasRedirects(&page, testTarget())
+		require.Equal(t, []interface{}{"/logseq-pages/first-alias", "/logseq-pages/second"}, page.frontMatter["aliases"])
+	})
+
+	t.Run("ignores pages without aliases", func(t *testing.T) {
+		page := testPage(map[string]string{"title": "A"})
+		addAliasRedirects(&page, testTarget())
+		require.NotContains(t, page.frontMatter, "aliases")
+	})
+}
+
+func TestReplacePageLinks(t *testing.T) {
+	titleToSlug := map[string]string{"public page": "public-page"}
+	content := "[[Public Page]] and [[Private Page]] and [[Private Page]]"
+
+	t.Run("keeps links to unpublished pages by default", func(t *testing.T) {
+		result, dangling := replacePageLinks(content, titleToSlug, testTarget(), testConfig())
+		require.Equal(t, "[Public Page](/logseq-pages/public-page) and [[Private Page]] and [[Private Page]]", result)
+		require.Equal(t, []string{"Private Page"}, dangling)
+	})
+
+	t.Run("renders links to unpublished pages as text", func(t *testing.T) {
+		config := testConfig()
+		config.UnpublishedLinks = unpublishedLinksText
+		result, _ := replacePageLinks(content, titleToSlug, testTarget(), config)
+		require.Equal(t, "[Public Page](/logseq-pages/public-page) and Private Page and Private Page", result)
+	})
+
+	t.Run("renders links to unpublished pages as span", func(t *testing.T) {
+		config := testConfig()
+		config.UnpublishedLinks = unpublishedLinksSpan
+		result, _ := replacePageLinks("[[Q&A]]", titleToSlug, testTarget(), config)
+		require.Equal(t, `<span class="private-link">Q&amp;A</span>`, result)
+	})
+}
+
+func TestBuildBacklinks(t *testing.T) {
+	pages := []parsedPage{
+		{pc: parsedContent{attributes: map[string]string{"title": "B", "slug": "b"}, links: []string{"A", "a", "Private"}}},
+		{pc: parsedContent{attributes: map[string]string{"title": "A", "slug": "a"}, links: []string{"A"}}},
+		{pc: parsedContent{attributes: map[string]string{"title": "C", "slug": "c"}, links: []string{"B", "A"}}},
+	}
+	titleToSlug := buildTitleToSlug(pages)
+
+	result := buildBacklinks(pages, titleToSlug, testTarget())
+
+	require.Equal(t, map[string][]backlink{
+		"a": {{title: "B", url: "/logseq-pages/b"}, {title: "C", url: "/logseq-pages/c"}},
+		"b": {{title: "C", url: "/logseq-pages/c"}},
+	}, result)
+}
+
+func TestAddBacklinks(t *testing.T) {
+	backlinks := []backlink{{title: "B", url: "/logseq-pages/b"}, {title: "C", url: "/logseq-pages/c"}}
+
+	t.Run("adds backlinks to front matter", func(t *testing.T) {
+		config := testConfig()
+		config.Backlinks = backlinksFrontMatter
+		page := testPage(map[string]string{"title": "A"})
+		content := addBacklinks(&page, "content", backlinks, config)
+		require.Equal(t, "content", content)
+		require.Equal(t, []interface{}{
+			map[string]interface{}{"title": "B", "url": "/logseq-pages/b"},
+			map[string]interface{}{"title": "C", "url": "/logseq-pages/c"},
+		}, page.frontMatter["backlinks"])
+	})
+
+	t.Run("adds backlinks section", func(t *testing.T) {
+		config := testConfig()
+		config.Backlinks = backlinksSection
+		page := testPage(map[string]string{"title": "A"})
+		content := addBacklinks(&page, "\ncontent\n", backlinks, config)
+		require.Equal(t, "\ncontent\n\n## Linked references\n\n- [B](/logseq-pages/b)\n- [C](/logseq-pages/c)\n", content)
+		require.NotContains(t, page.frontMatter, "backlinks")
+	})
+
+	t.Run("doesn't add backlinks by default", func(t *testing.T) {
+		page := testPage(map[string]string{"title": "A"})
+		require.Equal(t, "content", addBacklinks(&page, "content", backlinks, testConfig()))
+		require.NotContains(t, page.frontMatter, "backlinks")
+	})
+}
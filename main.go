@@ -1,85 +1,168 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
 	"io/fs"
 	"log"
 	"os"
-	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
 
 	"github.com/spf13/afero"
-	"golang.org/x/exp/slices"
 )
 
 /* textFile captures all data about a text file stored on disk that we need for exporting logseq graph */
 type textFile struct {
 	absoluteFSPath string
 	content        string
+	// journal is set only for pages loaded from the journals folder
+	journal *journal
 }
 
 type parsedContent struct {
 	/* content without attributes */
 	content    string
+	outline    outline
 	attributes map[string]string
 	assets     []string
+	// links are names of pages linked with `[[page]]`
+	links []string
 }
 
 type parsedPage struct {
 	exportFilename string
 	originalPath   string
 	pc             parsedContent
+	// frontMatter contains values generated by the exporter (e.g. backlinks), page properties are in pc.attributes
+	frontMatter map[string]interface{}
 }
 
-const publicAttributeSubstring = "public::"
-
-func loadPublicPages(appFS afero.Fs, logseqFolder string) ([]textFile, error) {
-	logseqPagesFolder := filepath.Join(logseqFolder, "pages")
-	// Find all files that contain `public::`
-	var publicFiles []string
-	err := afero.Walk(appFS, logseqPagesFolder, func(path string, info fs.FileInfo, walkError error) error {
+func findFiles(appFS afero.Fs, folder string) ([]string, error) {
+	var files []string
+	err := afero.Walk(appFS, folder, func(path string, info fs.FileInfo, walkError error) error {
 		if walkError != nil {
 			return walkError
 		}
 		if info.IsDir() {
 			return nil
 		}
-		file, err := appFS.OpenFile(path, os.O_RDONLY, os.ModePerm)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-		fileScanner := bufio.NewScanner(file)
-		for fileScanner.Scan() {
-			line := fileScanner.Text()
-			if strings.Contains(line, publicAttributeSubstring) {
-				publicFiles = append(publicFiles, path)
-				return nil
-			}
-		}
+		files = append(files, path)
 		return nil
 	})
 	// FIXME: test this error
 	if err != nil {
-		return nil, fmt.Errorf("error during walking through the logseq folder (%q): %w", logseqPagesFolder, err)
+		return nil, fmt.Errorf("error during walking through the logseq folder (%q): %w", folder, err)
+	}
+	return files, nil
+}
+
+func readTextFile(appFS afero.Fs, path string) (textFile, error) {
+	srcContent, err := afero.ReadFile(appFS, path)
+	if err != nil {
+		return textFile{}, fmt.Errorf("reading the %q file failed: %w", path, err)
+	}
+	santitizedContent := strings.ReplaceAll(string(srcContent), "\r", "")
+	return textFile{
+		absoluteFSPath: path,
+		content:        santitizedContent,
+	}, nil
+}
+
+/*
+loadPages reads all pages and journals from the logseq graph, including the non-public ones.
+We need the non-public pages to resolve block references.
+*/
+func loadPages(appFS afero.Fs, logseqFolder string, config graphConfig) ([]textFile, error) {
+	return readPages(appFS, logseqFolder, config, nil)
+}
+
+/*
+reloadPages is loadPages for the watch mode, it reads only new files and the changed files
+The other pages are the same as in the previous load, removed files are left out.
+*/
+func reloadPages(appFS afero.Fs, logseqFolder string, config graphConfig, previous []textFile, changed []string) ([]textFile, error) {
+	loaded := make(map[string]textFile, len(previous))
+	for _, page := range previous {
+		loaded[page.absoluteFSPath] = page
+	}
+	for _, path := range changed {
+		delete(loaded, path)
+	}
+	return readPages(appFS, logseqFolder, config, loaded)
+}
+
+// readPages lists pages and journals in the logseq folder and reads the ones that are not loaded yet
+func readPages(appFS afero.Fs, logseqFolder string, config graphConfig, loaded map[string]textFile) ([]textFile, error) {
+	pageFiles, err := findFiles(appFS, filepath.Join(logseqFolder, "pages"))
+	if err != nil {
+		return nil, err
 	}
-	pages := make([]textFile, 0, len(publicFiles))
-	for _, publicFile := range publicFiles {
-		srcContent, err := afero.ReadFile(appFS, publicFile)
+	pages := make([]textFile, 0, len(pageFiles))
+	for _, pageFile := range pageFiles {
+		if page, ok := loaded[pageFile]; ok {
+			pages = append(pages, page)
+			continue
+		}
+		page, err := readTextFile(appFS, pageFile)
 		if err != nil {
-			return nil, fmt.Errorf("reading the %q file failed: %w", publicFile, err)
+			return nil, err
 		}
-		santitizedContent := strings.ReplaceAll(string(srcContent), "\r", "")
-		pages = append(pages, textFile{
-			absoluteFSPath: publicFile,
-			content:        santitizedContent,
-		})
+		pages = append(pages, page)
+	}
+
+	// the journals folder is optional
+	logseqJournalsFolder := filepath.Join(logseqFolder, "journals")
+	if exists, _ := afero.DirExists(appFS, logseqJournalsFolder); !exists {
+		return pages, nil
+	}
+	journalFiles, err := findFiles(appFS, logseqJournalsFolder)
+	if err != nil {
+		return nil, err
+	}
+	for _, journalFile := range journalFiles {
+		if page, ok := loaded[journalFile]; ok {
+			pages = append(pages, page)
+			continue
+		}
+		page, err := readTextFile(appFS, journalFile)
+		if err != nil {
+			return nil, err
+		}
+		page.journal, err = parseJournal(filepath.Base(journalFile), config)
+		if err != nil {
+			log.Printf("treating %q as a regular page: %v", journalFile, err)
+		}
+		pages = append(pages, page)
 	}
 	return pages, nil
+}
 
+/*
+isPublic returns true if the page has the publicProperty page property (`public::`)
+with one of the publicValues (`true`). Only page properties at the start of the file are considered.
+*/
+func isPublic(page textFile, config *Config) bool {
+	value, ok := parseAttributes(page.content)[config.PublicProperty]
+	if !ok {
+		return false
+	}
+	for _, publicValue := range config.PublicValues {
+		if strings.EqualFold(value, publicValue) {
+			return true
+		}
+	}
+	return false
+}
+
+func filterPublicPages(pages []textFile, config *Config) []textFile {
+	publicPages := make([]textFile, 0, len(pages))
+	for _, page := range pages {
+		if isPublic(page, config) {
+			publicPages = append(publicPages, page)
+		}
+	}
+	return publicPages
 }
 
 func main() {
@@ -90,142 +173,202 @@ func main() {
 }
 
 func Run(args []string) error {
-	appFS := afero.NewOsFs()
 	config, err := parseConfig(args)
 	if err != nil {
 		return fmt.Errorf("the configuration could not be parsed: %w", err)
 	}
+	if config.DryRun {
+		changes, err := exportGraph(dryRunFs(), config)
+		if err != nil {
+			return err
+		}
+		return printChanges(os.Stdout, changes, config.Diff)
+	}
+	if config.Watch {
+		return watchGraph(args, config)
+	}
+	_, err = exportGraph(afero.NewOsFs(), config)
+	return err
+}
 
-	publicPages, err := loadPublicPages(appFS, config.LogseqFolder)
+/*
+exportGraph exports all public pages from the logseq folder to the output folder
+In dry run, it returns the changes that the export would make in the output folder.
+*/
+func exportGraph(appFS afero.Fs, config *Config) ([]outputChange, error) {
+	graphConfig, pages, err := loadGraph(appFS, config.LogseqFolder)
 	if err != nil {
-		return fmt.Errorf("Error during walking through a folder %v", err)
+		return nil, err
 	}
+	return exportPages(appFS, config, graphConfig, pages)
+}
 
-	// parse pages
-	parsedPages := make([]parsedPage, 0, len(publicPages))
-	for _, publicPage := range publicPages {
-		parsedPages = append(parsedPages, parsePage(publicPage))
+// loadGraph reads the logseq config and all pages from the logseq folder
+func loadGraph(appFS afero.Fs, logseqFolder string) (graphConfig, []textFile, error) {
+	graphConfig, err := loadGraphConfig(appFS, logseqFolder)
+	if err != nil {
+		return graphConfig, nil, err
 	}
 
-	err = exportAssets(appFS, config.OutputFolder, parsedPages)
+	pages, err := loadPages(appFS, logseqFolder, graphConfig)
 	if err != nil {
-		return fmt.Errorf("failed to export assets: %w", err)
+		return graphConfig, nil, fmt.Errorf("Error during walking through a folder %v", err)
 	}
+	return graphConfig, pages, nil
+}
 
-	titleToSlug := map[string]string{}
-	for _, p := range parsedPages {
-		titleToSlug[p.pc.attributes["title"]] = p.pc.attributes["slug"]
+// exportPages exports the loaded pages, it writes only pages that changed since the previous export
+func exportPages(appFS afero.Fs, config *Config, graphConfig graphConfig, pages []textFile) ([]outputChange, error) {
+	t, err := newTarget(config)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, page := range parsedPages {
-		exportPath := filepath.Join(config.OutputFolder, "logseq-pages", page.exportFilename)
-		folder, _ := filepath.Split(exportPath)
-		err = appFS.MkdirAll(folder, os.ModePerm)
-		if err != nil {
-			return fmt.Errorf("creating parent directory for %q failed: %v", exportPath, err)
-		}
-		// TODO: more processing on the content (linking pages, attributes)
-		contentWithAssets := replaceAssetPaths(page)
-		links := detectPageLinks(contentWithAssets)
-		for _, l := range links {
-			slug, ok := titleToSlug[l]
-			if !ok {
-				continue
-			}
-			contentWithAssets = strings.ReplaceAll(
-				contentWithAssets,
-				fmt.Sprintf("[[%s]]", l),
-				// we use path here on purpose since we create URL
-				fmt.Sprintf("[%s](%s)", l, path.Join("/logseq-pages", slug)),
-			)
-		}
-		// TODO find out what properties should I not quote
-		err = afero.WriteFile(
-			appFS,
-			exportPath,
-			[]byte(render(transformAttributes(page.pc.attributes, config.UnquotedProperties), contentWithAssets)),
-			0644,
-		)
-		if err != nil {
-			return fmt.Errorf("copying file %q failed: %v", exportPath, err)
-		}
+	frontMatterFormat := config.FrontMatter
+	if frontMatterFormat == "" {
+		frontMatterFormat = t.frontMatterFormat()
+	}
+	encoder, err := newFrontMatterEncoder(frontMatterFormat)
+	if err != nil {
+		return nil, err
 	}
-	return nil
-}
 
-func transformAttributes(attributes map[string]string, dontQuote []string) map[string]string {
-	dontQuote = append(dontQuote, "tags")
-	if _, ok := attributes["tags"]; ok {
-		attributes["tags"] = fmt.Sprintf("[%s]", attributes["tags"])
+	exports, assets, err := renderPages(appFS, config, t, graphConfig, pages)
+	if err != nil {
+		return nil, err
 	}
-	for name, value := range attributes {
-		if !slices.Contains(dontQuote, name) {
-			attributes[name] = fmt.Sprintf("%q", value)
-		}
+
+	var report exportReport
+	for _, e := range exports {
+		report.addMissingAssets(e.page, e.source, assets)
+		report.addUnpublishedLinks(e.source, e.dangling)
+	}
+	report.log()
+	// we check the report before writing anything so that a failed export doesn't change the output folder
+	if err := report.check(config); err != nil {
+		return nil, err
 	}
-	return attributes
-}
 
-func detectPageLinks(content string) []string {
-	result := regexp.MustCompile(`\[\[([^\/\n\r]+?)]]`).FindAllStringSubmatch(content, -1)
-	links := make([]string, 0, len(result))
-	for _, r := range result {
-		links = append(links, r[1])
+	writer, err := newOutputWriter(appFS, config.OutputFolder, config)
+	if err != nil {
+		return nil, err
 	}
-	return links
-}
+	exportAssets(writer, assets)
 
-func exportAssets(appFS afero.Fs, outputFolder string, exportPages []parsedPage) error {
-	// get all asset paths (deduplicated)
-	assetFullPaths := map[string]struct{}{}
-	for _, page := range exportPages {
-		for _, assetPath := range page.pc.assets {
-			fullPath := filepath.Clean(filepath.Join(filepath.Dir(page.originalPath), assetPath))
-			assetFullPaths[fullPath] = struct{}{}
+	rendered := 0
+	for _, e := range exports {
+		if e.kept {
+			writer.keepPage(e.previous.path, e.previous.entry)
+			continue
+		}
+		rendered++
+		values := t.frontMatter(e.page, frontMatterValues(e.page, config.PropertyTypes))
+		output, err := render(encoder, values, e.content)
+		if err != nil {
+			return nil, fmt.Errorf("rendering page %q failed: %w", e.page.originalPath, err)
+		}
+		err = writer.writePage(t.pagePath(e.page), e.entry(), []byte(output))
+		if err != nil {
+			return nil, err
 		}
 	}
+	if rendered < len(exports) {
+		log.Printf("rendered %d of %d public pages, the other pages didn't change since the previous export", rendered, len(exports))
+	}
+	return writer.finish()
+}
 
-	assetOutputFolder := filepath.Join(outputFolder, "logseq-assets")
+/*
+renderPages renders the public pages that changed since the previous export
 
-	assetSrcAndDest := map[string]string{}
-	for fullPath := range assetFullPaths {
-		dest := filepath.Join(assetOutputFolder, filepath.Base(fullPath))
-		assetSrcAndDest[fullPath] = dest
+A page is rendered again if its source changed, if the source of a page that it embeds or references changed,
+or if a linked page, its backlinks, or a linked asset changed. The other pages are restored from the manifest
+without parsing them and their output from the previous export is kept.
+*/
+func renderPages(appFS afero.Fs, config *Config, t target, graphConfig graphConfig, pages []textFile) ([]pageExport, map[string]exportedAsset, error) {
+	previous, err := previousPages(appFS, config)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	err := appFS.MkdirAll(assetOutputFolder, os.ModePerm)
-	if err != nil {
-		log.Fatalf("Error when making assets folder %q: %v", assetOutputFolder, err)
+	privateBlocks := newPrivateBlockMatcher(config)
+	blockIndex := buildBlockIndex(pages, config, privateBlocks)
+	pageIndex := buildPageIndex(pages)
+	publicPages := filterPublicPages(pages, config)
+	hashes := sourceHashes(pages)
+
+	parse := func(publicPage textFile) parsedPage {
+		page := parsePage(publicPage)
+		expandEmbeds(&page.pc.outline, page.pc.attributes["title"], pageIndex, blockIndex, config)
+		if redacted := redactPrivateBlocks(&page.pc.outline, privateBlocks); redacted > 0 {
+			log.Printf("redacted %d private blocks from %q", redacted, page.originalPath)
+		}
+		resolveOutlineBlockRefs(&page.pc.outline, blockIndex, config)
+		exposeBlockProperties(&page.pc.outline, config)
+		page.pc.render()
+		if config.MergeInlineTags {
+			mergeInlineTags(&page)
+		}
+		if config.AliasRedirects {
+			addAliasRedirects(&page, t)
+		}
+		return page
 	}
 
-	for src, dest := range assetSrcAndDest {
-		err = copy(appFS, src, dest)
-		if err != nil {
-			log.Printf("failed copying asset from %q to %q: %v", src, dest, err)
+	// parse pages, pages whose inputs didn't change are restored from the manifest
+	exports := make([]pageExport, 0, len(publicPages))
+	for _, publicPage := range publicPages {
+		e := pageExport{
+			source:    publicPage,
+			inputHash: inputHash(publicPage, pageDependencies(publicPage, pageIndex, blockIndex), hashes, graphConfig),
+		}
+		if p, ok := previous[publicPage.absoluteFSPath]; ok && p.entry.InputHash == e.inputHash {
+			e.previous = &p
+			e.page = p.restore()
+		} else {
+			e.page = parse(publicPage)
+			e.parsed = true
 		}
+		exports = append(exports, e)
 	}
-	return nil
-}
 
-func replaceAssetPaths(p parsedPage) string {
-	newContent := p.pc.content
-	for _, link := range p.pc.assets {
-		fileName := filepath.Base(link)
-		// we do want to use `path` package here, we are creating web URL
-		newContent = strings.ReplaceAll(newContent, link, path.Join("/logseq-assets", fileName))
+	parsedPages := make([]parsedPage, 0, len(exports))
+	for _, e := range exports {
+		parsedPages = append(parsedPages, e.page)
 	}
-	return newContent
-}
+	assets := collectAssets(appFS, t, config, parsedPages)
+	titleToSlug := buildTitleToSlug(parsedPages)
+	backlinks := buildBacklinks(parsedPages, titleToSlug, t)
 
-func render(attributes map[string]string, content string) string {
-	sortedKeys := make([]string, 0, len(attributes))
-	for k := range attributes {
-		sortedKeys = append(sortedKeys, k)
+	for i := range exports {
+		e := &exports[i]
+		slug := e.page.pc.attributes["slug"]
+		tags := detectTags(e.page.pc.content)
+		if !e.parsed {
+			tags = e.previous.entry.Tags
+		}
+		e.contextHash = contextHash(e.page, tags, titleToSlug, backlinks[slug], assets)
+		if e.previous != nil && e.previous.entry.ContextHash == e.contextHash && outputUnchanged(appFS, config.OutputFolder, *e.previous) {
+			e.kept = true
+			e.dangling = e.previous.entry.Dangling
+			continue
+		}
+		if !e.parsed {
+			e.page = parse(e.source)
+			e.parsed = true
+		}
+		content, dangling := replacePageLinks(replaceTags(replaceImageSizes(replaceAssetPaths(e.page, assets, t, config), t), titleToSlug, t, config), titleToSlug, t, config)
+		e.content = addBacklinks(&e.page, content, backlinks[slug], config)
+		e.dangling = dangling
 	}
-	slices.Sort(sortedKeys)
-	attributeBuilder := strings.Builder{}
-	for _, key := range sortedKeys {
-		attributeBuilder.WriteString(fmt.Sprintf("%s: %s\n", key, attributes[key]))
+	return exports, assets, nil
+}
+
+func detectPageLinks(content string) []string {
+	result := regexp.MustCompile(`\[\[([^\n\r]+?)]]`).FindAllStringSubmatch(content, -1)
+	links := make([]string, 0, len(result))
+	for _, r := range result {
+		links = append(links, r[1])
 	}
-	return fmt.Sprintf("---\n%s---\n%s", attributeBuilder.String(), content)
+	return links
 }
@@ -15,6 +15,24 @@ import (
 // get path to the directory where this test file lives
 var testDir, _ = os.Getwd()
 
+func testTarget() target {
+	t, err := newTarget(testConfig())
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func testConfig() *Config {
+	config := defaultConfig()
+	return &config
+}
+
+var testGraphConfig = graphConfig{
+	journalFileNameFormat:  defaultJournalFileNameFormat,
+	journalPageTitleFormat: defaultJournalPageTitleFormat,
+}
+
 func TestLoadPublicPages(t *testing.T) {
 	appFS := afero.NewMemMapFs()
 	// create test files and directories
@@ -25,7 +43,8 @@ func TestLoadPublicPages(t *testing.T) {
 	afero.WriteFile(appFS, "/src/pages/c", []byte("non public file"), 0644)
 
 	t.Run("it finds files with 'public::' string in them", func(t *testing.T) {
-		matchingFiles, err := loadPublicPages(appFS, "/src")
+		pages, err := loadPages(appFS, "/src", testGraphConfig)
+		matchingFiles := filterPublicPages(pages, testConfig())
 
 		require.Nil(t, err)
 		require.Len(t, matchingFiles, 1)
@@ -39,7 +58,8 @@ func TestLoadPublicPagesStripsAwayCarriageReturn(t *testing.T) {
 	appFS.MkdirAll("/src/pages", 0755)
 	afero.WriteFile(appFS, "/src/pages/b", []byte("public:: true\r\n- a bullet point"), 0644)
 
-	matchingFiles, err := loadPublicPages(appFS, "/src")
+	pages, err := loadPages(appFS, "/src", testGraphConfig)
+	matchingFiles := filterPublicPages(pages, testConfig())
 
 	require.Nil(t, err)
 	require.Len(t, matchingFiles, 1)
@@ -47,6 +67,100 @@ func TestLoadPublicPagesStripsAwayCarriageReturn(t *testing.T) {
 	require.Equal(t, "public:: true\n- a bullet point", matchingFiles[0].content)
 }
 
+func TestLoadPagesLoadsNonPublicPages(t *testing.T) {
+	appFS := afero.NewMemMapFs()
+	appFS.MkdirAll("/src/pages", 0755)
+	afero.WriteFile(appFS, "/src/pages/a", []byte("public:: true"), 0644)
+	afero.WriteFile(appFS, "/src/pages/b", []byte("- non public file"), 0644)
+
+	pages, err := loadPages(appFS, "/src", testGraphConfig)
+
+	require.Nil(t, err)
+	require.Len(t, pages, 2)
+	require.True(t, isPublic(pages[0], testConfig()))
+	require.False(t, isPublic(pages[1], testConfig()))
+}
+
+func TestReloadPages(t *testing.T) {
+	appFS := afero.NewMemMapFs()
+	appFS.MkdirAll("/src/pages", 0755)
+	appFS.MkdirAll("/src/journals", 0755)
+	afero.WriteFile(appFS, "/src/pages/a", []byte("- a"), 0644)
+	afero.WriteFile(appFS, "/src/pages/b", []byte("- b"), 0644)
+	afero.WriteFile(appFS, "/src/pages/c", []byte("- c"), 0644)
+	afero.WriteFile(appFS, "/src/journals/2023_07_29.md", []byte("- journal"), 0644)
+	previous, err := loadPages(appFS, "/src", testGraphConfig)
+	require.NoError(t, err)
+
+	afero.WriteFile(appFS, "/src/pages/a", []byte("- changed a"), 0644)
+	afero.WriteFile(appFS, "/src/pages/b", []byte("- changed b, but no event"), 0644)
+	appFS.Remove("/src/pages/c")
+	afero.WriteFile(appFS, "/src/pages/d", []byte("- new d"), 0644)
+
+	pages, err := reloadPages(appFS, "/src", testGraphConfig, previous, []string{"/src/pages/a", "/src/pages/c"})
+
+	require.NoError(t, err)
+	contents := make([]string, 0, len(pages))
+	for _, page := range pages {
+		contents = append(contents, page.content)
+	}
+	require.Equal(t, []string{"- changed a", "- b", "- new d", "- journal"}, contents, "only changed and new files are read")
+	require.Equal(t, "Jul 29th, 2023", pages[3].journal.title)
+}
+
+func TestIsPublic(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		content  string
+		expected bool
+	}{
+		{desc: "public:: true", content: "public:: true\n\n- text", expected: true},
+		{desc: "page without trailing new line", content: "title:: a\npublic:: true", expected: true},
+		{desc: "case-insensitive value", content: "public:: True\n", expected: true},
+		{desc: "public:: false", content: "public:: false\n\n- text", expected: false},
+		{desc: "empty value", content: "public::\n\n- text", expected: false},
+		{desc: "no public property", content: "title:: a\n\n- text", expected: false},
+		{desc: "block property", content: "- text\n  public:: true", expected: false},
+		{desc: "property in nested block", content: "title:: a\n\n- text\n\t- public:: true", expected: false},
+		{desc: "code sample", content: "- ```\n  public:: true\n  ```", expected: false},
+		{desc: "property after the first block", content: "- text\n\npublic:: true", expected: false},
+		{desc: "inline text", content: "- the public:: true property exports pages", expected: false},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			require.Equal(t, tC.expected, isPublic(textFile{content: tC.content}, testConfig()))
+		})
+	}
+
+	t.Run("uses configured property and values", func(t *testing.T) {
+		config := testConfig()
+		config.PublicProperty = "publish"
+		config.PublicValues = []string{"yes", "true"}
+		require.True(t, isPublic(textFile{content: "publish:: yes\n"}, config))
+		require.True(t, isPublic(textFile{content: "publish:: true\n"}, config))
+		require.False(t, isPublic(textFile{content: "public:: true\n"}, config))
+	})
+}
+
+func TestLoadPublicPagesLoadsJournals(t *testing.T) {
+	appFS := afero.NewMemMapFs()
+	appFS.MkdirAll("/src/pages", 0755)
+	appFS.MkdirAll("/src/journals", 0755)
+	afero.WriteFile(appFS, "/src/journals/2023_07_29.md", []byte("public:: true\n- a journal entry"), 0644)
+	afero.WriteFile(appFS, "/src/journals/2023_07_30.md", []byte("- private journal entry"), 0644)
+	afero.WriteFile(appFS, "/src/journals/not-a-date.md", []byte("public:: true"), 0644)
+
+	pages, err := loadPages(appFS, "/src", testGraphConfig)
+	matchingFiles := filterPublicPages(pages, testConfig())
+
+	require.Nil(t, err)
+	require.Len(t, matchingFiles, 2)
+	require.Equal(t, filepath.Join("/src", "journals", "2023_07_29.md"), matchingFiles[0].absoluteFSPath)
+	require.Equal(t, "Jul 29th, 2023", matchingFiles[0].journal.title)
+	require.Equal(t, filepath.Join("/src", "journals", "not-a-date.md"), matchingFiles[1].absoluteFSPath)
+	require.Nil(t, matchingFiles[1].journal, "journal with unparseable name is exported as a regular page")
+}
+
 func expectIdenticalContent(t testing.TB, expectedPath, actualPath string) {
 	t.Helper()
 
@@ -76,29 +190,16 @@ func makeRelative(t testing.TB, parent string, paths []string) []string {
 
 var expectedAssets = []string{
 	filepath.Join("logseq-assets", "img-1.jpg"),
+	filepath.Join("logseq-assets", "paper.pdf"),
 	filepath.Join("logseq-assets", "picture-2.png"),
 }
 
 var expectedPages = []string{
 	filepath.Join("logseq-pages", "2023-07-29-not-so-complex.md"),
+	filepath.Join("logseq-pages", "2023-07-30.md"),
 	filepath.Join("logseq-pages", "a.md"),
 	filepath.Join("logseq-pages", "b.md"),
-}
-
-func TestTransformAttributes(t *testing.T) {
-	attributes := map[string]string{
-		"tags":     "tag1, another-tag",
-		"quoted":   "quoted",
-		"unquoted": "unquoted",
-	}
-
-	result := transformAttributes(attributes, []string{"unquoted"})
-
-	require.Equal(t, map[string]string{
-		"tags":     "[tag1, another-tag]",
-		"quoted":   "\"quoted\"",
-		"unquoted": "unquoted",
-	}, result)
+	filepath.Join("logseq-pages", "projects-alpha.md"),
 }
 
 func TestFullTransformation(t *testing.T) {
@@ -120,7 +221,7 @@ func TestFullTransformation(t *testing.T) {
 	t.Run("the files are moved correctly", func(t *testing.T) {
 		require.Equal(
 			t,
-			append(expectedAssets, expectedPages...),
+			append(append([]string{manifestFileName}, expectedAssets...), expectedPages...),
 			makeRelative(t, testOutputFolder, actualFiles),
 			"The list of files in output folder is different from what the test expected",
 		)
@@ -138,41 +239,6 @@ func TestFullTransformation(t *testing.T) {
 	})
 }
 
-func TestRender(t *testing.T) {
-	t.Run("it renders attributes as quoted strings", func(t *testing.T) {
-		attributes := map[string]string{
-			"first":  "1",
-			"second": "2",
-		}
-		content := "page text"
-		result := render(attributes, content)
-		require.Equal(t, `---
-first: 1
-second: 2
----
-page text`, result)
-	})
-	t.Run("it renders attributes in alphabetical order", func(t *testing.T) {
-		attributes := map[string]string{
-			"e": "1",
-			"d": "1",
-			"c": "1",
-			"b": "1",
-			"a": "1",
-		}
-		content := "page text"
-		result := render(attributes, content)
-		require.Equal(t, `---
-a: 1
-b: 1
-c: 1
-d: 1
-e: 1
----
-page text`, result)
-	})
-}
-
 func listFilesInFolder(t *testing.T, folderPath string) []string {
 	t.Helper()
 	var files []string
@@ -231,4 +297,22 @@ TotT is a funny example of [[Environment design]] where Google decided to promot
 	result := detectPageLinks(content)
 
 	require.Equal(t, []string{"Environment design", "Automated testing", "Winters, Manshreck, Wright - Software Engineering at Google"}, result)
+
+	t.Run("detects namespaced links", func(t *testing.T) {
+		require.Equal(t, []string{"Projects/Alpha"}, detectPageLinks("see [[Projects/Alpha]]"))
+	})
+}
+
+func TestStrictExportFailsWithoutWriting(t *testing.T) {
+	outputFolder := t.TempDir()
+	err := Run([]string{
+		"logseq-export",
+		"--logseqFolder",
+		filepath.Join(testDir, "test", "logseq-folder"),
+		"--outputFolder",
+		outputFolder,
+		"--strict",
+	})
+	require.ErrorContains(t, err, "--strict")
+	require.Empty(t, listFilesInFolder(t, outputFolder))
 }
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// manifestFileName is the file in the output folder that records what the previous export produced
+const manifestFileName = ".logseq-export-manifest.json"
+
+/*
+manifest records all files produced by the export so that the next export
+only renders and writes files that changed and removes files whose source was removed or isn't public anymore
+Entries are keyed by the output path relative to the output folder (with forward slashes).
+*/
+type manifest struct {
+	ConfigHash string                   `json:"configHash"`
+	Pages      map[string]pageEntry     `json:"pages"`
+	Assets     map[string]manifestEntry `json:"assets"`
+}
+
+type manifestEntry struct {
+	Source string `json:"source"`
+	// SourceModTime and SourceSize decide if an asset changed
+	SourceModTime time.Time `json:"sourceModTime,omitempty"`
+	SourceSize    int64     `json:"sourceSize,omitempty"`
+	OutputHash    string    `json:"outputHash"`
+}
+
+/*
+pageEntry records what the page output depends on, so that the next export can skip the page if none of it changed,
+and the parts of the page that other pages need (title, slug, aliases, links, and assets), so that the skipped page doesn't have to be parsed
+*/
+type pageEntry struct {
+	manifestEntry
+	// InputHash covers the page source and sources of all pages that the page embeds or references
+	InputHash string `json:"inputHash"`
+	// ContextHash covers the slugs of linked pages, the backlinks, and the linked assets
+	ContextHash string   `json:"contextHash"`
+	Title       string   `json:"title"`
+	Slug        string   `json:"slug"`
+	Alias       string   `json:"alias,omitempty"`
+	Links       []string `json:"links,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Assets      []string `json:"assets,omitempty"`
+	// Dangling are links to pages that are not published, the export reports them for skipped pages as well
+	Dangling []string `json:"dangling,omitempty"`
+}
+
+func newManifest(configHash string) manifest {
+	return manifest{
+		ConfigHash: configHash,
+		Pages:      map[string]pageEntry{},
+		Assets:     map[string]manifestEntry{},
+	}
+}
+
+func hashBytes(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// configHash changes every time the export configuration changes (command line flags like --clean don't count)
+func configHash(config *Config) (string, error) {
+	serialized, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("hashing config failed: %w", err)
+	}
+	return hashBytes(serialized), nil
+}
+
+// loadManifest reads the manifest from the output folder, a missing manifest results in an empty manifest
+func loadManifest(appFS afero.Fs, outputFolder string) (manifest, error) {
+	content, err := afero.ReadFile(appFS, filepath.Join(outputFolder, manifestFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return newManifest(""), nil
+	}
+	if err != nil {
+		return manifest{}, fmt.Errorf("reading manifest failed: %w", err)
+	}
+	m := newManifest("")
+	if err := json.Unmarshal(content, &m); err != nil {
+		return manifest{}, fmt.Errorf("parsing manifest %q failed: %w", manifestFileName, err)
+	}
+	return m, nil
+}
+
+func (m manifest) save(appFS afero.Fs, outputFolder string) error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("serializing manifest failed: %w", err)
+	}
+	if err := appFS.MkdirAll(outputFolder, os.ModePerm); err != nil {
+		return fmt.Errorf("creating output folder %q failed: %w", outputFolder, err)
+	}
+	return afero.WriteFile(appFS, filepath.Join(outputFolder, manifestFileName), content, 0644)
+}
+
+/*
+outputWriter writes exported files to the output folder and records them in the current manifest
+Files that are the same as in the previous export are not written again.
+In dry run, the writer records all changes and doesn't remove any files.
+*/
+type outputWriter struct {
+	appFS        afero.Fs
+	outputFolder string
+	previous     manifest
+	current      manifest
+	dryRun       bool
+	changes      map[string]outputChange
+}
+
+func newOutputWriter(appFS afero.Fs, outputFolder string, config *Config) (*outputWriter, error) {
+	hash, err := configHash(config)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := loadManifest(appFS, outputFolder)
+	if err != nil {
+		return nil, err
+	}
+	w := &outputWriter{
+		appFS:        appFS,
+		outputFolder: outputFolder,
+		previous:     previous,
+		current:      newManifest(hash),
+		dryRun:       config.DryRun,
+		changes:      map[string]outputChange{},
+	}
+	if config.Clean {
+		if err := w.removeOutputs(w.previousOutputs()); err != nil {
+			return nil, err
+		}
+		w.previous = newManifest("")
+	}
+	return w, nil
+}
+
+// fileHash returns the hash of the file, or an empty string if it can't be read
+func fileHash(appFS afero.Fs, path string) string {
+	content, err := afero.ReadFile(appFS, path)
+	if err != nil {
+		return ""
+	}
+	return hashBytes(content)
+}
+
+// outputHash returns the hash of the file in the output folder, or an empty string if it can't be read
+func (w *outputWriter) outputHash(relativePath string) string {
+	return fileHash(w.appFS, filepath.Join(w.outputFolder, relativePath))
+}
+
+/*
+writePage writes the rendered page unless the file in the output folder already has the same content
+Outputs that the user edited are restored.
+*/
+func (w *outputWriter) writePage(relativePath string, entry pageEntry, content []byte) error {
+	key := filepath.ToSlash(relativePath)
+	entry.OutputHash = hashBytes(content)
+	w.current.Pages[key] = entry
+	exportPath := filepath.Join(w.outputFolder, relativePath)
+	existing, err := afero.ReadFile(w.appFS, exportPath)
+	exists := err == nil
+	if w.dryRun {
+		recordWrite(w.changes, key, existing, exists, content, false)
+	}
+	if exists && hashBytes(existing) == entry.OutputHash {
+		return nil
+	}
+	if err := w.appFS.MkdirAll(filepath.Dir(exportPath), os.ModePerm); err != nil {
+		return fmt.Errorf("creating parent directory for %q failed: %w", exportPath, err)
+	}
+	if err := afero.WriteFile(w.appFS, exportPath, content, 0644); err != nil {
+		return fmt.Errorf("writing file %q failed: %w", exportPath, err)
+	}
+	return nil
+}
+
+// keepPage records the page from the previous export that didn't change, the export checked that its output is still the same
+func (w *outputWriter) keepPage(relativePath string, entry pageEntry) {
+	w.current.Pages[filepath.ToSlash(relativePath)] = entry
+}
+
+// copyAsset copies the asset unless it didn't change since the previous export
+func (w *outputWriter) copyAsset(src, relativePath string) error {
+	return w.convertAsset(src, relativePath, nil)
+}
+
+/*
+convertAsset writes the converted asset unless the source didn't change since the previous export
+(same modification time and size), the configuration is the same, and the file in the output folder
+is the one that the previous export wrote. A nil convert copies the asset.
+*/
+func (w *outputWriter) convertAsset(src, relativePath string, convert func(content []byte) ([]byte, error)) error {
+	info, err := w.appFS.Stat(src)
+	if err != nil {
+		return err
+	}
+	key := filepath.ToSlash(relativePath)
+	previous, ok := w.previous.Assets[key]
+	if ok && previous.Source == src &&
+		previous.SourceModTime.Equal(info.ModTime()) &&
+		previous.SourceSize == info.Size() &&
+		w.previous.ConfigHash == w.current.ConfigHash &&
+		w.outputHash(relativePath) == previous.OutputHash {
+		w.current.Assets[key] = previous
+		return nil
+	}
+	dest := filepath.Join(w.outputFolder, relativePath)
+	if err := w.appFS.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return fmt.Errorf("creating assets folder for %q failed: %w", dest, err)
+	}
+	var existing []byte
+	existingErr := fs.ErrNotExist
+	if w.dryRun {
+		existing, existingErr = afero.ReadFile(w.appFS, dest)
+	}
+	if convert == nil {
+		if err := copy(w.appFS, src, dest); err != nil {
+			return err
+		}
+	} else {
+		source, err := afero.ReadFile(w.appFS, src)
+		if err != nil {
+			return err
+		}
+		converted, err := convert(source)
+		if err != nil {
+			return fmt.Errorf("converting asset %q failed: %w", src, err)
+		}
+		if err := afero.WriteFile(w.appFS, dest, converted, 0644); err != nil {
+			return fmt.Errorf("writing asset %q failed: %w", dest, err)
+		}
+	}
+	content, err := afero.ReadFile(w.appFS, dest)
+	if err != nil {
+		return err
+	}
+	if w.dryRun {
+		recordWrite(w.changes, key, existing, existingErr == nil, content, true)
+	}
+	w.current.Assets[key] = manifestEntry{
+		Source:        src,
+		SourceModTime: info.ModTime(),
+		SourceSize:    info.Size(),
+		OutputHash:    hashBytes(content),
+	}
+	return nil
+}
+
+// previousOutputs returns all files produced by the previous export
+func (w *outputWriter) previousOutputs() map[string]manifestEntry {
+	outputs := map[string]manifestEntry{}
+	for key, entry := range w.previous.Pages {
+		outputs[key] = entry.manifestEntry
+	}
+	for key, entry := range w.previous.Assets {
+		outputs[key] = entry
+	}
+	return outputs
+}
+
+// staleOutputs returns files from the previous export that the current export didn't produce
+func (w *outputWriter) staleOutputs() map[string]manifestEntry {
+	stale := map[string]manifestEntry{}
+	for key, entry := range w.previousOutputs() {
+		_, page := w.current.Pages[key]
+		_, asset := w.current.Assets[key]
+		if !page && !asset {
+			stale[key] = entry
+		}
+	}
+	return stale
+}
+
+/*
+removeOutputs removes files created by the previous export together with folders that become empty
+Files that changed since the export (their hash doesn't match the manifest) are kept,
+we don't want to delete anything that the user created or edited.
+*/
+func (w *outputWriter) removeOutputs(outputs map[string]manifestEntry) error {
+	keys := make([]string, 0, len(outputs))
+	for key := range outputs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		outputPath := filepath.Join(w.outputFolder, filepath.FromSlash(key))
+		content, err := afero.ReadFile(w.appFS, outputPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading output %q failed: %w", outputPath, err)
+		}
+		if hashBytes(content) != outputs[key].OutputHash {
+			log.Printf("keeping %q, the file changed after the previous export", outputPath)
+			continue
+		}
+		if w.dryRun {
+			w.changes[key] = outputChange{kind: changeDeleted, path: key}
+			continue
+		}
+		if err := w.appFS.Remove(outputPath); err != nil {
+			return fmt.Errorf("removing output %q failed: %w", outputPath, err)
+		}
+		log.Printf("removed %q", outputPath)
+		w.removeEmptyFolders(filepath.Dir(outputPath))
+	}
+	return nil
+}
+
+// removeEmptyFolders removes the folder and its parents if they are empty, it never removes the output folder
+func (w *outputWriter) removeEmptyFolders(folder string) {
+	outputFolder := filepath.Clean(w.outputFolder)
+	for folder = filepath.Clean(folder); folder != outputFolder && strings.HasPrefix(folder, outputFolder); folder = filepath.Dir(folder) {
+		empty, err := afero.IsEmpty(w.appFS, folder)
+		if err != nil || !empty {
+			return
+		}
+		if err := w.appFS.Remove(folder); err != nil {
+			return
+		}
+	}
+}
+
+// finish removes stale outputs and saves the manifest, it returns the planned changes in dry run
+func (w *outputWriter) finish() ([]outputChange, error) {
+	if err := w.removeOutputs(w.staleOutputs()); err != nil {
+		return nil, err
+	}
+	if w.dryRun {
+		return sortedChanges(w.changes), nil
+	}
+	return nil, w.current.save(w.appFS, w.outputFolder)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputWriter(t *testing.T) {
+	config := testConfig()
+	config.OutputFolder = "/out"
+	source := pageEntry{manifestEntry: manifestEntry{Source: "/graph/pages/a.md"}}
+
+	export := func(appFS afero.Fs, pages map[string]string) {
+		t.Helper()
+		writer, err := newOutputWriter(appFS, "/out", config)
+		require.NoError(t, err)
+		for path, content := range pages {
+			require.NoError(t, writer.writePage(path, source, []byte(content)))
+		}
+		require.NoError(t, writer.copyAsset("/graph/assets/image.png", filepath.Join("logseq-assets", "image.png")))
+		_, err = writer.finish()
+		require.NoError(t, err)
+	}
+
+	t.Run("records outputs in the manifest", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(appFS, "/graph/assets/image.png", []byte("png"), 0644))
+
+		export(appFS, map[string]string{"logseq-pages/a.md": "content"})
+
+		m, err := loadManifest(appFS, "/out")
+		require.NoError(t, err)
+		require.Equal(t, hashBytes([]byte("content")), m.Pages["logseq-pages/a.md"].OutputHash)
+		require.Equal(t, source.Source, m.Pages["logseq-pages/a.md"].Source)
+		require.Equal(t, "/graph/assets/image.png", m.Assets["logseq-assets/image.png"].Source)
+	})
+
+	t.Run("doesn't write unchanged files", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(appFS, "/graph/assets/image.png", []byte("png"), 0644))
+		export(appFS, map[string]string{"logseq-pages/a.md": "content"})
+
+		past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, appFS.Chtimes("/out/logseq-pages/a.md", past, past))
+		require.NoError(t, appFS.Chtimes("/out/logseq-assets/image.png", past, past))
+		export(appFS, map[string]string{"logseq-pages/a.md": "content"})
+
+		for _, path := range []string{"/out/logseq-pages/a.md", "/out/logseq-assets/image.png"} {
+			info, err := appFS.Stat(path)
+			require.NoError(t, err)
+			require.True(t, info.ModTime().Equal(past), "%s was written again", path)
+		}
+
+		export(appFS, map[string]string{"logseq-pages/a.md": "new content"})
+		page, err := afero.ReadFile(appFS, "/out/logseq-pages/a.md")
+		require.NoError(t, err)
+		require.Equal(t, "new content", string(page))
+	})
+
+	t.Run("restores edited outputs", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(appFS, "/graph/assets/image.png", []byte("png"), 0644))
+		export(appFS, map[string]string{"logseq-pages/a.md": "content"})
+
+		require.NoError(t, afero.WriteFile(appFS, "/out/logseq-pages/a.md", []byte("changed output"), 0644))
+		require.NoError(t, afero.WriteFile(appFS, "/out/logseq-assets/image.png", []byte("changed output"), 0644))
+		export(appFS, map[string]string{"logseq-pages/a.md": "content"})
+
+		page, err := afero.ReadFile(appFS, "/out/logseq-pages/a.md")
+		require.NoError(t, err)
+		require.Equal(t, "content", string(page))
+		asset, err := afero.ReadFile(appFS, "/out/logseq-assets/image.png")
+		require.NoError(t, err)
+		require.Equal(t, "png", string(asset))
+	})
+
+	t.Run("removes stale outputs", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(appFS, "/graph/assets/image.png", []byte("png"), 0644))
+		require.NoError(t, afero.WriteFile(appFS, "/out/user-file.md", []byte("user file"), 0644))
+		export(appFS, map[string]string{"logseq-pages/a.md": "content", "logseq-pages/b.md": "content"})
+
+		export(appFS, map[string]string{"logseq-pages/a.md": "content"})
+
+		_, err := appFS.Stat("/out/logseq-pages/b.md")
+		require.Error(t, err)
+		_, err = appFS.Stat("/out/logseq-pages/a.md")
+		require.NoError(t, err)
+		_, err = appFS.Stat("/out/user-file.md")
+		require.NoError(t, err, "files that the export didn't create stay in the output folder")
+	})
+
+	t.Run("keeps stale outputs changed after the export", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(appFS, "/graph/assets/image.png", []byte("png"), 0644))
+		export(appFS, map[string]string{"logseq-pages/a.md": "content", "logseq-pages/b.md": "content"})
+		require.NoError(t, afero.WriteFile(appFS, "/out/logseq-pages/b.md", []byte("edited by user"), 0644))
+
+		export(appFS, map[string]string{"logseq-pages/a.md": "content"})
+
+		_, err := appFS.Stat("/out/logseq-pages/b.md")
+		require.NoError(t, err)
+	})
+
+	t.Run("removes folders that become empty", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(appFS, "/graph/assets/image.png", []byte("png"), 0644))
+		export(appFS, map[string]string{"logseq-pages/a.md": "content", "_posts/b.md": "content"})
+
+		export(appFS, map[string]string{"logseq-pages/a.md": "content"})
+
+		_, err := appFS.Stat("/out/_posts")
+		require.Error(t, err)
+		_, err = appFS.Stat("/out")
+		require.NoError(t, err)
+	})
+
+	t.Run("clean removes all previous outputs", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(appFS, "/graph/assets/image.png", []byte("png"), 0644))
+		require.NoError(t, afero.WriteFile(appFS, "/out/user-file.md", []byte("user file"), 0644))
+		export(appFS, map[string]string{"logseq-pages/a.md": "content"})
+
+		config.Clean = true
+		defer func() { config.Clean = false }()
+		writer, err := newOutputWriter(appFS, "/out", config)
+		require.NoError(t, err)
+
+		_, err = appFS.Stat("/out/logseq-pages/a.md")
+		require.Error(t, err)
+		_, err = appFS.Stat("/out/logseq-assets/image.png")
+		require.Error(t, err)
+		_, err = appFS.Stat("/out/user-file.md")
+		require.NoError(t, err)
+		require.Empty(t, writer.previous.Pages)
+	})
+
+	t.Run("dry run records changes without removing files", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(appFS, "/graph/assets/image.png", []byte("png"), 0644))
+		export(appFS, map[string]string{"logseq-pages/a.md": "content", "logseq-pages/b.md": "b"})
+
+		config.DryRun = true
+		defer func() { config.DryRun = false }()
+		overlay := afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(appFS), afero.NewMemMapFs())
+		writer, err := newOutputWriter(overlay, "/out", config)
+		require.NoError(t, err)
+		require.NoError(t, writer.writePage("logseq-pages/a.md", source, []byte("new content")))
+		require.NoError(t, writer.writePage("logseq-pages/c.md", source, []byte("c")))
+		require.NoError(t, writer.copyAsset("/graph/assets/image.png", filepath.Join("logseq-assets", "image.png")))
+		changes, err := writer.finish()
+		require.NoError(t, err)
+
+		require.Equal(t, []outputChange{
+			{kind: changeModified, path: "logseq-pages/a.md", before: []byte("content"), after: []byte("new content")},
+			{kind: changeDeleted, path: "logseq-pages/b.md"},
+			{kind: changeCreated, path: "logseq-pages/c.md", after: []byte("c")},
+		}, changes)
+		page, err := afero.ReadFile(appFS, "/out/logseq-pages/a.md")
+		require.NoError(t, err)
+		require.Equal(t, "content", string(page))
+		_, err = appFS.Stat("/out/logseq-pages/b.md")
+		require.NoError(t, err)
+	})
+
+	t.Run("dry run with clean plans only real changes", func(t *testing.T) {
+		appFS := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(appFS, "/graph/assets/image.png", []byte("png"), 0644))
+		export(appFS, map[string]string{"logseq-pages/a.md": "content", "logseq-pages/b.md": "b"})
+
+		config.DryRun = true
+		config.Clean = true
+		defer func() { config.DryRun = false; config.Clean = false }()
+		overlay := afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(appFS), afero.NewMemMapFs())
+		writer, err := newOutputWriter(overlay, "/out", config)
+		require.NoError(t, err)
+		require.NoError(t, writer.writePage("logseq-pages/a.md", source, []byte("content")))
+		require.NoError(t, writer.copyAsset("/graph/assets/image.png", filepath.Join("logseq-assets", "image.png")))
+		changes, err := writer.finish()
+		require.NoError(t, err)
+
+		require.Equal(t, []outputChange{{kind: changeDeleted, path: "logseq-pages/b.md"}}, changes)
+	})
+}
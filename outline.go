@@ -0,0 +1,269 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+/* block is a single Logseq bullet point with its children */
+type block struct {
+	// level is the depth of the block in the exported outline, top-level blocks have level 0
+	level int
+	// indentation is the whitespace in front of the bullet point as it is in the Logseq file
+	indentation string
+	/* content lines without the bullet point and indentation */
+	content    []string
+	properties map[string]string
+	children   []*block
+}
+
+/* outline is the page content parsed into a tree of blocks */
+type outline struct {
+	// preamble contains lines before the first bullet point
+	preamble []string
+	blocks   []*block
+}
+
+// tabWidth is the number of spaces that Logseq uses for one level of indentation (:export/bullet-indentation :two-spaces)
+const tabWidth = 2
+
+var bulletRegexp = regexp.MustCompile(`^(\s*)-(?: (.*))?$`)
+
+var blockPropertyRegexp = regexp.MustCompile(`^\s*([\w\-]+)::\s*(.*?)\s*$`)
+
+var fenceRegexp = regexp.MustCompile("^\\s*(```|~~~)")
+
+/*
+text returns the first line of the block.
+This is the same text that Logseq shows for block references (unless `:ui/show-full-blocks?` is enabled).
+*/
+func (b *block) text() string {
+	return b.content[0]
+}
+
+// hasText returns true if the block has any content (block properties are not content)
+func (b *block) hasText() bool {
+	for _, line := range b.content {
+		if line != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// copy returns a deep copy of the block and all its children
+func (b *block) copy() *block {
+	result := &block{
+		level:       b.level,
+		indentation: b.indentation,
+		content:     append([]string{}, b.content...),
+		properties:  map[string]string{},
+		children:    make([]*block, 0, len(b.children)),
+	}
+	for k, v := range b.properties {
+		result.properties[k] = v
+	}
+	for _, c := range b.children {
+		result.children = append(result.children, c.copy())
+	}
+	return result
+}
+
+// setLevel changes the level of the block and shifts all its children accordingly
+func (b *block) setLevel(level int) {
+	b.level = level
+	for _, c := range b.children {
+		c.setLevel(level + 1)
+	}
+}
+
+// walkBlocks calls fn for every block in the tree (parents before children)
+func walkBlocks(blocks []*block, fn func(b *block)) {
+	for _, b := range blocks {
+		fn(b)
+		walkBlocks(b.children, fn)
+	}
+}
+
+func indentationWidth(indentation string) int {
+	width := 0
+	for _, c := range indentation {
+		if c == '\t' {
+			width += tabWidth
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// unindent removes leading whitespace from the line, but at most `width` columns
+func unindent(line string, width int) string {
+	column := 0
+	i := 0
+	for ; i < len(line) && column < width; i++ {
+		switch line[i] {
+		case ' ':
+			column++
+		case '\t':
+			column += tabWidth
+		default:
+			return line[i:]
+		}
+	}
+	return line[i:]
+}
+
+/*
+parseOutline turns page content (without page properties) into a tree of blocks
+
+A block is a child of the closest preceding block with smaller indentation.
+All lines that don't start with a bullet point belong to the preceding block
+(lines in fenced code blocks never start a new block).
+*/
+func parseOutline(content string) outline {
+	var result outline
+	// stack of the current block and all its parents
+	var stack []*block
+	insideFence := false
+	for _, line := range strings.Split(content, "\n") {
+		if !insideFence {
+			if match := bulletRegexp.FindStringSubmatch(line); match != nil {
+				b := &block{
+					indentation: match[1],
+					content:     []string{match[2]},
+					properties:  map[string]string{},
+				}
+				width := indentationWidth(b.indentation)
+				for len(stack) > 0 && indentationWidth(stack[len(stack)-1].indentation) >= width {
+					stack = stack[:len(stack)-1]
+				}
+				if len(stack) == 0 {
+					// top-level blocks can be indented when the page starts with nested bullet points
+					b.level = width / tabWidth
+					result.blocks = append(result.blocks, b)
+				} else {
+					parent := stack[len(stack)-1]
+					b.level = parent.level + 1
+					parent.children = append(parent.children, b)
+				}
+				stack = append(stack, b)
+				insideFence = fenceRegexp.MatchString(match[2])
+				continue
+			}
+		}
+		if len(stack) == 0 {
+			result.preamble = append(result.preamble, line)
+			continue
+		}
+		current := stack[len(stack)-1]
+		blockLine := unindent(line, indentationWidth(current.indentation)+len("- "))
+		if fenceRegexp.MatchString(blockLine) {
+			insideFence = !insideFence
+		}
+		current.content = append(current.content, blockLine)
+	}
+	walkBlocks(result.blocks, extractBlockProperties)
+	return result
+}
+
+/*
+extractBlockProperties moves block properties from the block content to the block properties map
+
+Logseq puts block properties right after the first line of the block:
+
+  - block text
+    collapsed:: true
+    id:: 64c4f1a2-2c2b-4a3f-9a3c-7a9b2d7c1e3f
+    second line of the block
+
+Blocks without text start with the properties. Blocks that start with a code fence
+have the properties after the closing fence, the `key:: value` lines inside the code are part of the code:
+
+  - ```js
+    console.log("code")
+    ```
+    id:: 64c4f1a2-2c2b-4a3f-9a3c-7a9b2d7c1e3f
+*/
+func extractBlockProperties(b *block) {
+	start := 1
+	if fenceRegexp.MatchString(b.content[0]) {
+		start = closingFence(b.content) + 1
+		if start == 0 {
+			// the code isn't closed, all lines are code
+			return
+		}
+	} else if blockPropertyRegexp.MatchString(b.content[0]) {
+		start = 0
+	}
+	end := start
+	for ; end < len(b.content); end++ {
+		match := blockPropertyRegexp.FindStringSubmatch(b.content[end])
+		if match == nil {
+			break
+		}
+		b.properties[match[1]] = match[2]
+	}
+	b.content = append(b.content[:start], b.content[end:]...)
+	if len(b.content) == 0 {
+		// the block contained only properties
+		b.content = []string{""}
+	}
+}
+
+// closingFence returns the index of the line closing the code fence that starts the lines, -1 if there is none
+func closingFence(lines []string) int {
+	for i := 1; i < len(lines); i++ {
+		if fenceRegexp.MatchString(lines[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+/*
+render turns the outline into markdown
+
+- top-level blocks become paragraphs
+- all other blocks become bullet points shifted by one level to the left
+- multi-line blocks (e.g. code blocks) are without the indentation of the bullet point
+*/
+func (o outline) render() string {
+	lines := append([]string{}, o.preamble...)
+	for _, b := range o.blocks {
+		lines = renderBlock(lines, b)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func renderBlock(lines []string, b *block) []string {
+	first, rest := b.content[0], b.content[1:]
+	if b.level == 0 {
+		if first != "" {
+			// top-level blocks are separated by an empty line
+			first = "\n" + first
+		}
+		lines = append(lines, first)
+		lines = append(lines, rest...)
+	} else {
+		indentation := strings.Repeat("\t", b.level-1)
+		if first != "" {
+			first = indentation + "- " + first
+		}
+		lines = append(lines, first)
+		for _, line := range rest {
+			if line != "" {
+				line = indentation + "  " + line
+			}
+			lines = append(lines, line)
+		}
+	}
+	if b.level == 0 && len(b.children) > 0 && b.children[0].content[0] != "" && lines[len(lines)-1] != "" {
+		// the list needs an empty line after the paragraph, kramdown continues the paragraph otherwise
+		lines = append(lines, "")
+	}
+	for _, c := range b.children {
+		lines = renderBlock(lines, c)
+	}
+	return lines
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutline(t *testing.T) {
+	t.Run("keeps lines before the first block", func(t *testing.T) {
+		result := parseOutline("intro\n\n- first\n- second")
+		require.Equal(t, []string{"intro", ""}, result.preamble)
+		require.Len(t, result.blocks, 2)
+		require.Equal(t, []string{"first"}, result.blocks[0].content)
+		require.Equal(t, []string{"second"}, result.blocks[1].content)
+	})
+
+	t.Run("nests blocks based on indentation", func(t *testing.T) {
+		result := parseOutline("- a\n\t- b\n\t\t- c\n\t- d\n- e")
+		require.Len(t, result.blocks, 2)
+		a := result.blocks[0]
+		require.Len(t, a.children, 2)
+		require.Equal(t, "b", a.children[0].text())
+		require.Equal(t, 1, a.children[0].level)
+		require.Equal(t, "c", a.children[0].children[0].text())
+		require.Equal(t, 2, a.children[0].children[0].level)
+		require.Equal(t, "d", a.children[1].text())
+		require.Equal(t, "e", result.blocks[1].text())
+	})
+
+	t.Run("handles mixed tab and space indentation", func(t *testing.T) {
+		result := parseOutline("- a\n  - b\n\t- c\n    - d")
+		a := result.blocks[0]
+		require.Len(t, a.children, 2)
+		require.Equal(t, "b", a.children[0].text())
+		require.Equal(t, "c", a.children[1].text())
+		require.Equal(t, "d", a.children[1].children[0].text())
+	})
+
+	t.Run("parses multi-line blocks", func(t *testing.T) {
+		result := parseOutline("- first\n\t- nested\n\t  second line\n\t  id:: 64c4f1a2-2c2b-4a3f-9a3c-7a9b2d7c1e3f")
+		nested := result.blocks[0].children[0]
+		require.Equal(t, "\t", nested.indentation)
+		require.Equal(t, []string{"nested", "second line", "id:: 64c4f1a2-2c2b-4a3f-9a3c-7a9b2d7c1e3f"}, nested.content)
+		require.Empty(t, nested.properties, "properties have to follow the first line")
+	})
+
+	t.Run("moves block properties out of the content", func(t *testing.T) {
+		result := parseOutline("- text\n  collapsed:: true\n  background-color:: yellow\n  second line\n- id:: 64c4f1a2-2c2b-4a3f-9a3c-7a9b2d7c1e3f")
+		require.Equal(t, []string{"text", "second line"}, result.blocks[0].content)
+		require.Equal(t, map[string]string{"collapsed": "true", "background-color": "yellow"}, result.blocks[0].properties)
+		require.Equal(t, []string{""}, result.blocks[1].content)
+		require.Equal(t, map[string]string{"id": "64c4f1a2-2c2b-4a3f-9a3c-7a9b2d7c1e3f"}, result.blocks[1].properties)
+	})
+
+	t.Run("doesn't start blocks or properties inside fenced code", func(t *testing.T) {
+		result := parseOutline("- ```yaml\n  - list item\n  key:: value\n  ```\n- second")
+		require.Len(t, result.blocks, 2)
+		require.Equal(t, []string{"```yaml", "- list item", "key:: value", "```"}, result.blocks[0].content)
+		require.Empty(t, result.blocks[0].properties)
+	})
+
+	t.Run("extracts properties after code that starts the block", func(t *testing.T) {
+		result := parseOutline("- ```yaml\n  key:: value\n  ```\n  id:: 64c4f1a2-2c2b-4a3f-9a3c-7a9b2d7c1e3f\n- second")
+		require.Equal(t, []string{"```yaml", "key:: value", "```"}, result.blocks[0].content)
+		require.Equal(t, map[string]string{"id": "64c4f1a2-2c2b-4a3f-9a3c-7a9b2d7c1e3f"}, result.blocks[0].properties)
+	})
+
+	t.Run("starts with top-level blocks indented", func(t *testing.T) {
+		result := parseOutline("\t\t- a\n\t\t\t- b")
+		require.Equal(t, 2, result.blocks[0].level)
+		require.Equal(t, 3, result.blocks[0].children[0].level)
+	})
+}
+
+func TestBlockText(t *testing.T) {
+	b := parseOutline("- collapsed:: true\n  block text\n  second line").blocks[0]
+	require.Equal(t, "block text", b.text())
+	require.True(t, b.hasText())
+	require.False(t, parseOutline("- id:: 64c4f1a2-2c2b-4a3f-9a3c-7a9b2d7c1e3f").blocks[0].hasText())
+}
+
+func TestRenderOutline(t *testing.T) {
+	t.Run("renders code fences in third-level bullet points", func(t *testing.T) {
+		result := parseOutline("- a\n\t- b\n\t\t- ```js\n\t\t  const a = 1;\n\t\t  - not a bullet point\n\t\t  ```\n\t\t- c")
+		require.Equal(t, "\na\n\n- b\n\t- ```js\n\t  const a = 1;\n\t  - not a bullet point\n\t  ```\n\t- c", result.render())
+	})
+
+	t.Run("renders blocks indented with spaces", func(t *testing.T) {
+		result := parseOutline("- a\n  - b\n    - c\n      second line")
+		require.Equal(t, "\na\n\n- b\n\t- c\n\t  second line", result.render())
+	})
+
+	t.Run("separates a paragraph from its nested list", func(t *testing.T) {
+		result := parseOutline("- paragraph\n\t- item\n\t\t- nested item\n- next paragraph")
+		require.Equal(t, "\nparagraph\n\n- item\n\t- nested item\n\nnext paragraph", result.render())
+	})
+
+	t.Run("renders copied blocks on a new level", func(t *testing.T) {
+		b := parseOutline("- a\n\t- b").blocks[0].copy()
+		b.setLevel(1)
+		require.Equal(t, "- a\n\t- b", outline{blocks: []*block{b}}.render())
+	})
+}
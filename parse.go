@@ -11,31 +11,56 @@ import (
 
 func parsePage(publicPage textFile) parsedPage {
 	pc := parseContent(publicPage.content)
+	journal := publicPage.journal
+	// add date attribute to journals if missing
+	if _, ok := pc.attributes["date"]; !ok && journal != nil {
+		pc.attributes["date"] = journal.date.Format(journalDateLayout)
+	}
 	exportFilename := getExportFilename(publicPage.absoluteFSPath, pc.attributes)
 	// add slug attribute if missing
 	if _, ok := pc.attributes["slug"]; !ok {
+		// journal file names (2023_07_29.md) are replaced by the ISO date (2023-07-29.md)
+		if journal != nil {
+			exportFilename = fmt.Sprintf("%s.md", journal.date.Format(journalDateLayout))
+		}
 		pc.attributes["slug"] = filenameWithoutExt(exportFilename)
 	}
 	// add title attribute if missing
-	title, ok := pc.attributes["title"]
-	if !ok {
-		fileName := filepath.Base(publicPage.absoluteFSPath)
-		title = getTitleFromFilename(fileName)
-	}
-	pc.attributes["title"] = title
+	pc.attributes["title"] = getTitle(publicPage, pc.attributes)
 	return parsedPage{
 		exportFilename: exportFilename,
 		originalPath:   publicPage.absoluteFSPath,
 		pc:             pc,
+		frontMatter:    map[string]interface{}{},
+	}
+}
+
+/*
+getTitle returns the page name as Logseq shows it:
+the title:: page property, the journal title, or the unescaped file name
+*/
+func getTitle(page textFile, attributes map[string]string) string {
+	if title, ok := attributes["title"]; ok {
+		return title
+	}
+	if page.journal != nil {
+		return page.journal.title
 	}
+	return getTitleFromFilename(filepath.Base(page.absoluteFSPath))
 }
 
 func filenameWithoutExt(filename string) string {
 	return filename[:len(filename)-len(filepath.Ext(filename))]
 }
 
+/*
+getTitleFromFilename turns the file name into the Logseq page name
+
+Logseq stores namespaced pages (`Projects/Alpha`) either as `Projects___Alpha.md`
+(`:file/name-format :triple-lowbar`) or as `Projects%2FAlpha.md` (legacy format).
+*/
 func getTitleFromFilename(orig string) string {
-	nameOnly := filenameWithoutExt(orig)
+	nameOnly := strings.ReplaceAll(filenameWithoutExt(orig), "___", "%2F")
 	unescaped, err := url.QueryUnescape(nameOnly)
 	if err != nil {
 		log.Printf("note name %q can't be unescaped because the %% sign is not followed by two hexadecimal characters", orig)
@@ -47,10 +72,33 @@ func getTitleFromFilename(orig string) string {
 func sanitizeName(orig string) string {
 	ext := filepath.Ext(orig)
 	title := getTitleFromFilename(orig)
+	return strings.Join([]string{slugify(title), ext}, "")
+}
 
+// slugify turns page name into a lowercase string usable in URL (`Hello World` -> `hello-world`)
+func slugify(name string) string {
 	nonWordChars := regexp.MustCompile(`\W+`)
-	sanitizedName := strings.ToLower(nonWordChars.ReplaceAllString(title, "-"))
-	return strings.Join([]string{sanitizedName, ext}, "")
+	return strings.ToLower(nonWordChars.ReplaceAllString(name, "-"))
+}
+
+/*
+parseAliases parses the `alias::` page property
+`alias:: first, [[second alias]]` returns `first` and `second alias`
+*/
+func parseAliases(attributes map[string]string) []string {
+	value, ok := attributes["alias"]
+	if !ok {
+		return nil
+	}
+	var aliases []string
+	for _, alias := range strings.Split(value, ",") {
+		alias = strings.TrimSpace(alias)
+		alias = strings.TrimSuffix(strings.TrimPrefix(alias, "[["), "]]")
+		if alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
 }
 
 func getExportFilename(originalPath string, attributes map[string]string) string {
@@ -70,7 +118,7 @@ func getExportFilename(originalPath string, attributes map[string]string) string
 	return fmt.Sprintf("%s.md", slug)
 }
 
-var attrAndContentRegexp = regexp.MustCompile(`^((?:.*?::.*\n)*)\n?((?:.|\s)+)?$`)
+var attrAndContentRegexp = regexp.MustCompile(`^((?:.*?::.*(?:\n|$))*)\n?((?:.|\s)+)?$`)
 
 var dateLinkRegexp = regexp.MustCompile(`^\s*\[\[([^]]+?)]]\s*$`)
 
@@ -95,81 +143,49 @@ func stripAttributes(rawContent string) string {
 	return result[2]
 }
 
-func removeEmptyBulletPoints(from string) string {
-	return regexp.MustCompile(`(?m:^\s*-\s*$)`).ReplaceAllString(from, "")
-}
-
-func firstBulletPointsToParagraphs(from string) string {
-	return regexp.MustCompile(`(?m:^- )`).ReplaceAllString(from, "\n")
+func parseContent(rawContent string) parsedContent {
+	pc := parsedContent{
+		attributes: parseAttributes(rawContent),
+		outline:    parseOutline(stripAttributes(rawContent)),
+	}
+	pc.render()
+	return pc
 }
 
-func removeTabFromMultiLevelBulletPoints(from string) string {
-	return regexp.MustCompile(`(?m:^\t{1,}[^\t])`).ReplaceAllStringFunc(from, func(s string) string {
-		return s[1:]
-	})
+// render updates the content, assets, and links after the outline changed
+func (pc *parsedContent) render() {
+	pc.content = pc.outline.render()
+	pc.assets = parseAssets(pc.content)
+	pc.links = detectPageLinks(pc.content)
 }
 
-const multilineBlocks = `\n?(- .*\n(?:  .*\n?)+)`
-
 /*
-Makes sure that code blocks and multiline blocks are without any extra characters at the start of the line
-
-  - ```ts
-    const hello = "world"
-    ```
-
-is changed to
-
-```ts
-const hello = "world"
-```
+assetRegexp matches references to relative assets
+  - markdown images and links: `![img](../assets/img.jpg)`, `[paper](../assets/paper.pdf)`
+    with optional Logseq attributes `![img](../assets/img.jpg){:height 300, :width 400}`
+  - media macros: `{{pdf ../assets/paper.pdf}}`, `{{video ../assets/clip.mp4}}`, `{{audio ../assets/song.mp3}}`
 */
-func unindentMultilineStrings(from string) string {
-	return regexp.MustCompile(multilineBlocks).ReplaceAllStringFunc(from, func(s string) string {
-		match := regexp.MustCompile(multilineBlocks).FindStringSubmatch(s)
-		onlyBlock := match[1]
-		replacement := regexp.MustCompile(`((?m:^[- ] ))`).ReplaceAllString(onlyBlock, "") // remove the leading spaces or dash
-		replacedString := strings.Replace(s, onlyBlock, replacement, 1)
-		return fmt.Sprintf("\n%s", replacedString) // add extra new line
-	})
-}
-
-func applyStringTransformers(from string, transformers ...func(string) string) string {
-	result := from
-	for _, t := range transformers {
-		result = t(result)
-	}
-	return result
-}
+var assetRegexp = regexp.MustCompile(`(!?)\[([^\]\n]*)]\((\.\.?/[^)\n]+?)\)(\{:[^}\n]*})?|\{\{(pdf|video|audio) +(\.\.?/[^}\n]+?) *}}`)
 
-func parseContent(rawContent string) parsedContent {
-	content := applyStringTransformers(rawContent,
-		stripAttributes,
-		removeEmptyBulletPoints,
-		unindentMultilineStrings,
-		firstBulletPointsToParagraphs,
-		// since we turned the first bullet points to paragraphs
-		// we shift all bullet points by one tab to the left
-		removeTabFromMultiLevelBulletPoints,
-	)
-	return parsedContent{
-		attributes: parseAttributes(rawContent),
-		content:    content,
-		assets:     parseAssets(rawContent),
+// assetLink returns the relative path from the assetRegexp submatches
+func assetLink(submatches []string) string {
+	if submatches[3] != "" {
+		return submatches[3]
 	}
+	return submatches[6]
 }
 
 /*
-parseAssets finds all paths to relative markdown images
+parseAssets finds all paths to relative assets
 ![img](../assets/img.jpg) - returns `../assets/img.jpg`
+{{pdf ../assets/paper.pdf}} - returns `../assets/paper.pdf`
 ![img](http://example.com/img/jpg) - is ignored
 */
 func parseAssets(content string) []string {
-	assetRegexp := regexp.MustCompile(`!\[.*?]\((\.\.?/.+?)\)`)
 	links := assetRegexp.FindAllStringSubmatch(content, -1)
 	assets := make([]string, 0, len(links))
 	for _, l := range links {
-		assets = append(assets, l[1])
+		assets = append(assets, assetLink(l))
 	}
 	return assets
 }
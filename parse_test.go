@@ -2,6 +2,7 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -46,6 +47,24 @@ func TestParseAssets(t *testing.T) {
 		require.Equal(t, 0, len(result))
 	})
 
+	t.Run("extracts relative links and media macros", func(t *testing.T) {
+		content := "- [paper](../assets/paper.pdf)\n- {{pdf ../assets/book.pdf}}\n- {{video ../assets/clip.mp4}}\n- {{audio ../assets/song.mp3}}"
+		result := parseAssets(content)
+		require.Equal(t, []string{"../assets/paper.pdf", "../assets/book.pdf", "../assets/clip.mp4", "../assets/song.mp3"}, result)
+	})
+
+	t.Run("extracts images with Logseq attributes", func(t *testing.T) {
+		content := "- ![img](../assets/image.png){:height 300, :width 400}"
+		result := parseAssets(content)
+		require.Equal(t, []string{"../assets/image.png"}, result)
+	})
+
+	t.Run("ignores absolute links and macros", func(t *testing.T) {
+		content := "- [site](https://example.com/paper.pdf)\n- {{video https://www.youtube.com/watch?v=id}}"
+		result := parseAssets(content)
+		require.Empty(t, result)
+	})
+
 	// TODO if first content line contains only image, move it to an image attribute (based on some config)
 }
 
@@ -77,6 +96,14 @@ func TestParsePage(t *testing.T) {
 		require.Equal(t, "Blog idea: All good laws that EU brought", result.pc.attributes["title"])
 	})
 
+	t.Run("uses namespace from the filename in the title", func(t *testing.T) {
+		for _, name := range []string{"/Projects___Alpha.md", "/Projects%2FAlpha.md"} {
+			result := parsePage(textFile{absoluteFSPath: name})
+			require.Equal(t, "Projects/Alpha", result.pc.attributes["title"])
+			require.Equal(t, "projects-alpha.md", result.exportFilename)
+		}
+	})
+
 	t.Run("uses sanitized filename as the exportFileName", func(t *testing.T) {
 		testPage := textFile{
 			absoluteFSPath: "/Blog idea%3A All good laws that EU brought.md",
@@ -113,6 +140,36 @@ func TestParsePage(t *testing.T) {
 		require.Equal(t, "slug-name", result.pc.attributes["slug"])
 	})
 
+	t.Run("uses journal date and title", func(t *testing.T) {
+		testPage := textFile{
+			absoluteFSPath: "/journals/2023_07_29.md",
+			content:        "",
+			journal: &journal{
+				date:  time.Date(2023, 7, 29, 0, 0, 0, 0, time.UTC),
+				title: "Jul 29th, 2023",
+			},
+		}
+		result := parsePage(testPage)
+		require.Equal(t, "Jul 29th, 2023", result.pc.attributes["title"])
+		require.Equal(t, "2023-07-29", result.pc.attributes["date"])
+		require.Equal(t, "2023-07-29", result.pc.attributes["slug"])
+		require.Equal(t, "2023-07-29.md", result.exportFilename)
+	})
+
+	t.Run("journal page properties take precedence", func(t *testing.T) {
+		testPage := textFile{
+			absoluteFSPath: "/journals/2023_07_29.md",
+			content:        "title:: My day\nslug:: my-day\n",
+			journal: &journal{
+				date:  time.Date(2023, 7, 29, 0, 0, 0, 0, time.UTC),
+				title: "Jul 29th, 2023",
+			},
+		}
+		result := parsePage(testPage)
+		require.Equal(t, "My day", result.pc.attributes["title"])
+		require.Equal(t, "2023-07-29-my-day.md", result.exportFilename)
+	})
+
 	t.Run("uses exportFilename as slug", func(t *testing.T) {
 		testPage := textFile{
 			absoluteFSPath: "/name with space.md",
@@ -181,6 +238,7 @@ func TestParseContent(t *testing.T) {
 `)
 		require.Equal(t, `
 ## If statement
+
 - ~~~bash
   if [-a file];then
     ...
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/afero"
+)
+
+const (
+	changeCreated  = "create"
+	changeModified = "modify"
+	changeDeleted  = "delete"
+)
+
+/*
+outputChange is a change of a file in the output folder that the export would make
+before and after contain the content of pages, they are empty for assets because we don't diff binary files.
+*/
+type outputChange struct {
+	kind string
+	// path is relative to the output folder (with forward slashes)
+	path   string
+	before []byte
+	after  []byte
+}
+
+/*
+dryRunFs returns a file system that reads from disk and keeps all writes in memory
+The export can run against it without touching the output folder.
+*/
+func dryRunFs() afero.Fs {
+	return afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(afero.NewOsFs()), afero.NewMemMapFs())
+}
+
+// sortedChanges returns the changes ordered by path
+func sortedChanges(changes map[string]outputChange) []outputChange {
+	result := make([]outputChange, 0, len(changes))
+	for _, change := range changes {
+		result = append(result, change)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].path < result[j].path })
+	return result
+}
+
+/*
+recordWrite adds the change that writing the content over the existing file makes
+A file that the plan deletes and writes again (e.g. with --clean) becomes modified or disappears from the plan if its content doesn't change.
+*/
+func recordWrite(changes map[string]outputChange, path string, existing []byte, exists bool, content []byte, binary bool) {
+	if exists && bytes.Equal(existing, content) {
+		// writing the same content again cancels a planned removal
+		delete(changes, path)
+		return
+	}
+	change := outputChange{kind: changeCreated, path: path}
+	if exists {
+		change.kind = changeModified
+	}
+	if !binary {
+		change.before = existing
+		change.after = content
+	}
+	changes[path] = change
+}
+
+// printChanges prints the change plan, with diff it prints a unified diff of every changed page
+func printChanges(w io.Writer, changes []outputChange, diff bool) error {
+	counts := map[string]int{}
+	for _, change := range changes {
+		counts[change.kind]++
+		if _, err := fmt.Fprintf(w, "%s %s\n", change.kind, change.path); err != nil {
+			return err
+		}
+		if !diff || (change.before == nil && change.after == nil) {
+			continue
+		}
+		unified, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        diffLines(change.before),
+			B:        diffLines(change.after),
+			FromFile: "a/" + change.path,
+			ToFile:   "b/" + change.path,
+			Context:  3,
+		})
+		if err != nil {
+			return fmt.Errorf("creating diff of %q failed: %w", change.path, err)
+		}
+		if _, err := io.WriteString(w, unified); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "dry run: %d files to create, %d to modify, %d to delete\n", counts[changeCreated], counts[changeModified], counts[changeDeleted])
+	return err
+}
+
+// diffLines splits the content into lines with line endings, difflib.SplitLines adds an extra empty line to content ending with a new line
+func diffLines(content []byte) []string {
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	return lines
+}
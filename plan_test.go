@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintChanges(t *testing.T) {
+	changes := []outputChange{
+		{kind: changeCreated, path: "logseq-assets/image.png"},
+		{kind: changeModified, path: "logseq-pages/a.md", before: []byte("title\nold line\n"), after: []byte("title\nnew line\n")},
+		{kind: changeDeleted, path: "logseq-pages/b.md"},
+	}
+
+	t.Run("prints the plan", func(t *testing.T) {
+		var out strings.Builder
+		require.NoError(t, printChanges(&out, changes, false))
+		require.Equal(t, `create logseq-assets/image.png
+modify logseq-pages/a.md
+delete logseq-pages/b.md
+dry run: 1 files to create, 1 to modify, 1 to delete
+`, out.String())
+	})
+
+	t.Run("prints diffs of changed pages", func(t *testing.T) {
+		var out strings.Builder
+		require.NoError(t, printChanges(&out, changes, true))
+		require.Equal(t, `create logseq-assets/image.png
+modify logseq-pages/a.md
+--- a/logseq-pages/a.md
++++ b/logseq-pages/a.md
+@@ -1,2 +1,2 @@
+ title
+-old line
++new line
+delete logseq-pages/b.md
+dry run: 1 files to create, 1 to modify, 1 to delete
+`, out.String())
+	})
+}
+
+func TestRecordWrite(t *testing.T) {
+	t.Run("cancels planned removal of a file written with the same content", func(t *testing.T) {
+		changes := map[string]outputChange{"a.md": {kind: changeDeleted, path: "a.md"}}
+		recordWrite(changes, "a.md", []byte("a"), true, []byte("a"), false)
+		require.Empty(t, changes)
+	})
+
+	t.Run("doesn't keep content of binary files", func(t *testing.T) {
+		changes := map[string]outputChange{}
+		recordWrite(changes, "image.png", nil, false, []byte("png"), true)
+		require.Equal(t, outputChange{kind: changeCreated, path: "image.png"}, changes["image.png"])
+	})
+}
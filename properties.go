@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	propertyTypeString  = "string"
+	propertyTypeNumber  = "number"
+	propertyTypeBoolean = "boolean"
+	propertyTypeDate    = "date"
+	propertyTypeList    = "list"
+)
+
+// builtInPropertyTypes are types of properties with a special meaning, the propertyTypes config overrides them
+var builtInPropertyTypes = map[string]string{
+	"title": propertyTypeString,
+	"slug":  propertyTypeString,
+	"tags":  propertyTypeList,
+	"alias": propertyTypeList,
+}
+
+var (
+	numberRegexp       = regexp.MustCompile(`^[+-]?\d+(?:\.\d+)?$`)
+	dateRegexp         = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	pageRefListRegexp  = regexp.MustCompile(`^\s*\[\[[^\]]+]](?:\s*,\s*\[\[[^\]]+]])*\s*$`)
+	pageRefBracketsReg = regexp.MustCompile(`^\[\[(.*)]]$`)
+)
+
+func validatePropertyType(propertyType string) error {
+	switch propertyType {
+	case propertyTypeString, propertyTypeNumber, propertyTypeBoolean, propertyTypeDate, propertyTypeList:
+		return nil
+	}
+	return fmt.Errorf("property type has to be one of %q, %q, %q, %q, or %q, got %q", propertyTypeString, propertyTypeNumber, propertyTypeBoolean, propertyTypeDate, propertyTypeList, propertyType)
+}
+
+/*
+typedPropertyValue turns the Logseq property value into a front matter value
+
+The type comes from the propertyTypes config, then from the builtInPropertyTypes.
+Other properties are typed automatically:
+
+	true, false          -> boolean
+	42, 3.14             -> number
+	2023-07-29           -> date
+	[[page a]], [[b]]    -> list
+	apples, oranges      -> list
+	everything else      -> string
+
+Numbers and dates are typed only if the front matter renders them the same as in Logseq,
+so `01234` (leading zero), `1.10` (trailing zero), `99999999999999999999` (too large), and `2023-02-30` stay strings.
+*/
+func typedPropertyValue(name, value string, propertyTypes map[string]string) interface{} {
+	propertyType, ok := propertyTypes[name]
+	if !ok {
+		propertyType, ok = builtInPropertyTypes[name]
+	}
+	if !ok {
+		return detectPropertyValue(value)
+	}
+	switch propertyType {
+	case propertyTypeList:
+		return parseListValue(value)
+	case propertyTypeNumber:
+		if n, ok := parseNumber(value); ok {
+			return n
+		}
+	case propertyTypeBoolean:
+		if b, err := strconv.ParseBool(strings.ToLower(value)); err == nil {
+			return b
+		}
+	case propertyTypeDate:
+		if isDate(value) {
+			return dateValue(value)
+		}
+	case propertyTypeString:
+		return value
+	}
+	log.Printf("value %q of the %q property is not a %s, exporting it as a string", value, name, propertyType)
+	return value
+}
+
+func detectPropertyValue(value string) interface{} {
+	switch strings.ToLower(value) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if n, ok := parseNumber(value); ok {
+		return n
+	}
+	if isDate(value) {
+		return dateValue(value)
+	}
+	if pageRefListRegexp.MatchString(value) || isCommaList(value) {
+		return parseListValue(value)
+	}
+	return value
+}
+
+// isDate returns true for existing dates in the `2023-07-29` format
+func isDate(value string) bool {
+	if !dateRegexp.MatchString(value) {
+		return false
+	}
+	_, err := time.Parse("2006-01-02", value)
+	return err == nil
+}
+
+// isCommaList returns true for comma separated values without empty items (`apples, oranges`)
+func isCommaList(value string) bool {
+	if !strings.Contains(value, ",") {
+		return false
+	}
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+parseNumber returns the number only if formatting it gives back the value
+The encoders would otherwise change the value (`01234` -> `1234`, `1.10` -> `1.1`, `99999999999999999999` -> `1e+20`).
+*/
+func parseNumber(value string) (interface{}, bool) {
+	if !numberRegexp.MatchString(value) {
+		return nil, false
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(i, 10) == value {
+		return i, true
+	}
+	// the YAML encoder formats floats with the 'g' format
+	if f, err := strconv.ParseFloat(value, 64); err == nil && strconv.FormatFloat(f, 'g', -1, 64) == value {
+		return f, true
+	}
+	return nil, false
+}
+
+// parseListValue splits the comma separated list (`tag1, [[tag 2]]`) and removes page reference brackets
+func parseListValue(value string) []interface{} {
+	items := []interface{}{}
+	for _, item := range strings.Split(value, ",") {
+		item = pageRefBracketsReg.ReplaceAllString(strings.TrimSpace(item), "$1")
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
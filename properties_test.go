@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedPropertyValue(t *testing.T) {
+	t.Run("detects types automatically", func(t *testing.T) {
+		testCases := []struct {
+			value    string
+			expected interface{}
+		}{
+			{"true", true},
+			{"False", false},
+			{"42", int64(42)},
+			{"-3.14", -3.14},
+			{"2023-07-29", dateValue("2023-07-29")},
+			{"[[page a]], [[page b]]", []interface{}{"page a", "page b"}},
+			{"[[single page]]", []interface{}{"single page"}},
+			{"apples, oranges", []interface{}{"apples", "oranges"}},
+			{"apples,, oranges", "apples,, oranges"},
+			{"01234", "01234"},
+			{"1.10", "1.10"},
+			{"+5", "+5"},
+			{"99999999999999999999", "99999999999999999999"},
+			{"2023-02-30", "2023-02-30"},
+			{"hello [[world]]", "hello [[world]]"},
+			{"2023-07-29 is a date", "2023-07-29 is a date"},
+		}
+		for _, tc := range testCases {
+			require.Equal(t, tc.expected, typedPropertyValue("property", tc.value, nil), tc.value)
+		}
+	})
+
+	t.Run("uses built-in types", func(t *testing.T) {
+		require.Equal(t, "2023", typedPropertyValue("slug", "2023", nil))
+		require.Equal(t, "Jul 30th, 2023", typedPropertyValue("title", "Jul 30th, 2023", nil))
+		require.Equal(t, []interface{}{"tag1", "multi word"}, typedPropertyValue("tags", "tag1, [[multi word]]", nil))
+	})
+
+	t.Run("uses configured types", func(t *testing.T) {
+		propertyTypes := map[string]string{
+			"isbn":   propertyTypeString,
+			"fruit":  propertyTypeList,
+			"rating": propertyTypeNumber,
+			"tags":   propertyTypeString,
+			"due":    propertyTypeDate,
+		}
+		require.Equal(t, "9780451524935", typedPropertyValue("isbn", "9780451524935", propertyTypes))
+		require.Equal(t, []interface{}{"apples", "oranges"}, typedPropertyValue("fruit", "apples, oranges", propertyTypes))
+		require.Equal(t, "tag1, tag2", typedPropertyValue("tags", "tag1, tag2", propertyTypes))
+		require.Equal(t, "not a number", typedPropertyValue("rating", "not a number", propertyTypes))
+		require.Equal(t, dateValue("2024-02-29"), typedPropertyValue("due", "2024-02-29", propertyTypes))
+		require.Equal(t, "2023-02-29", typedPropertyValue("due", "2023-02-29", propertyTypes))
+	})
+}
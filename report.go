@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+/* brokenLink is a link to a missing asset or to a page that is not published */
+type brokenLink struct {
+	page string
+	// line is the line in the source page, it's 0 if the link comes from embedded content
+	line int
+	link string
+}
+
+func (l brokenLink) location() string {
+	if l.line == 0 {
+		return l.page
+	}
+	return fmt.Sprintf("%s:%d", l.page, l.line)
+}
+
+/* exportReport collects broken links from all exported pages */
+type exportReport struct {
+	missingAssets    []brokenLink
+	unpublishedLinks []brokenLink
+}
+
+// lineNumber returns the first line (starting at 1) of the content that contains the text, 0 if there is none
+func lineNumber(content, text string) int {
+	for i, line := range strings.Split(content, "\n") {
+		if strings.Contains(line, text) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// addMissingAssets reports links from the page to assets that don't exist
+func (r *exportReport) addMissingAssets(p parsedPage, source textFile, assets map[string]exportedAsset) {
+	reported := map[string]bool{}
+	for _, link := range p.pc.assets {
+		if reported[link] || !assets[assetSourcePath(p, link)].missing {
+			continue
+		}
+		reported[link] = true
+		r.missingAssets = append(r.missingAssets, brokenLink{
+			page: source.absoluteFSPath,
+			line: lineNumber(source.content, link),
+			link: link,
+		})
+	}
+}
+
+// addUnpublishedLinks reports links from the page to pages that are not published
+func (r *exportReport) addUnpublishedLinks(source textFile, pageNames []string) {
+	for _, name := range pageNames {
+		r.unpublishedLinks = append(r.unpublishedLinks, brokenLink{
+			page: source.absoluteFSPath,
+			line: lineNumber(source.content, "[["+name+"]]"),
+			link: name,
+		})
+	}
+}
+
+func (r exportReport) log() {
+	for _, l := range sortedBrokenLinks(r.missingAssets) {
+		log.Printf("%s: missing asset %q", l.location(), l.link)
+	}
+	for _, l := range sortedBrokenLinks(r.unpublishedLinks) {
+		log.Printf("%s: link to a page that is not published %q", l.location(), l.link)
+	}
+}
+
+func sortedBrokenLinks(links []brokenLink) []brokenLink {
+	sorted := append([]brokenLink{}, links...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].page != sorted[j].page {
+			return sorted[i].page < sorted[j].page
+		}
+		return sorted[i].line < sorted[j].line
+	})
+	return sorted
+}
+
+/*
+check returns an error if the export has to stop because of the broken links
+With --strict, any broken link stops the export. With `unpublishedLinks: fail`, links to pages that are not published do.
+*/
+func (r exportReport) check(config *Config) error {
+	if config.Strict && len(r.missingAssets)+len(r.unpublishedLinks) > 0 {
+		return fmt.Errorf("found %d links to missing assets and %d links to pages that are not published (--strict)", len(r.missingAssets), len(r.unpublishedLinks))
+	}
+	if len(r.unpublishedLinks) > 0 && config.UnpublishedLinks == unpublishedLinksFail {
+		return fmt.Errorf("found %d links to pages that are not published (unpublishedLinks: %s)", len(r.unpublishedLinks), unpublishedLinksFail)
+	}
+	return nil
+}
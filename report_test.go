@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportReport(t *testing.T) {
+	source := textFile{
+		absoluteFSPath: "/graph/pages/a.md",
+		content:        "public:: true\n\n- ![img](../assets/missing.png)\n- ![img](../assets/image.png)\n- [[private page]]",
+	}
+	page := parsedPage{
+		originalPath: source.absoluteFSPath,
+		pc:           parsedContent{assets: []string{"../assets/missing.png", "../assets/image.png", "../assets/missing.png"}},
+	}
+	assets := map[string]exportedAsset{
+		"/graph/assets/missing.png": {missing: true},
+		"/graph/assets/image.png":   {},
+	}
+
+	var report exportReport
+	report.addMissingAssets(page, source, assets)
+	report.addUnpublishedLinks(source, []string{"private page"})
+
+	t.Run("collects broken links with their lines", func(t *testing.T) {
+		require.Equal(t, []brokenLink{{page: "/graph/pages/a.md", line: 3, link: "../assets/missing.png"}}, report.missingAssets)
+		require.Equal(t, []brokenLink{{page: "/graph/pages/a.md", line: 5, link: "private page"}}, report.unpublishedLinks)
+		require.Equal(t, "/graph/pages/a.md:3", report.missingAssets[0].location())
+	})
+
+	t.Run("doesn't fail by default", func(t *testing.T) {
+		require.NoError(t, report.check(testConfig()))
+	})
+
+	t.Run("fails in strict mode", func(t *testing.T) {
+		config := testConfig()
+		config.Strict = true
+		require.EqualError(t, report.check(config), "found 1 links to missing assets and 1 links to pages that are not published (--strict)")
+		require.NoError(t, exportReport{}.check(config))
+	})
+
+	t.Run("fails on unpublished links with unpublishedLinks: fail", func(t *testing.T) {
+		config := testConfig()
+		config.UnpublishedLinks = unpublishedLinksFail
+		require.Error(t, report.check(config))
+		require.NoError(t, exportReport{missingAssets: report.missingAssets}.check(config))
+	})
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tagRegexp matches `#tag` and `#[[multi word tag]]`, markdown headings (`# heading`) are not tags
+var tagRegexp = regexp.MustCompile(`(^|\s)#(?:\[\[([^\]\n]+)]]|([^\s#,.;:!?()\[\]"'` + "`" + `]+))`)
+
+/*
+mapTagLines calls fn for every line outside of fenced code blocks and replaces the line with the result
+Tags in code blocks (e.g. shell comments) are not tags.
+*/
+func mapTagLines(content string, fn func(line string) string) string {
+	lines := strings.Split(content, "\n")
+	insideFence := false
+	for i, line := range lines {
+		if fenceRegexp.MatchString(line) {
+			insideFence = !insideFence
+			continue
+		}
+		if !insideFence {
+			lines[i] = fn(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func tagName(match []string) string {
+	if match[2] != "" {
+		return match[2]
+	}
+	return match[3]
+}
+
+// detectTags returns all `#tag` and `#[[multi word tag]]` names in the content
+func detectTags(content string) []string {
+	var tags []string
+	mapTagLines(content, func(line string) string {
+		for _, match := range tagRegexp.FindAllStringSubmatch(line, -1) {
+			tags = append(tags, tagName(match))
+		}
+		return line
+	})
+	return tags
+}
+
+/*
+mergeInlineTags adds tags used in the page content to the `tags` page property
+`tags:: tag1` and content with `#tag2` result in `tags:: tag1, tag2`
+Existing tags lose their page reference brackets (`[[tag1]]`) the same way as in parseListValue.
+*/
+func mergeInlineTags(p *parsedPage) {
+	var tags []string
+	seen := map[string]bool{}
+	add := func(tag string) {
+		tag = pageRefBracketsReg.ReplaceAllString(strings.TrimSpace(tag), "$1")
+		if tag == "" || seen[normalizePageName(tag)] {
+			return
+		}
+		seen[normalizePageName(tag)] = true
+		tags = append(tags, tag)
+	}
+	if existing, ok := p.pc.attributes["tags"]; ok {
+		for _, tag := range strings.Split(existing, ",") {
+			add(tag)
+		}
+	}
+	for _, tag := range detectTags(p.pc.content) {
+		add(tag)
+	}
+	if len(tags) > 0 {
+		p.pc.attributes["tags"] = strings.Join(tags, ", ")
+	}
+}
+
+/*
+replaceTags turns tags into links based on the tagLinks config
+- page: links to the exported tag page, tags without an exported page stay unchanged
+- taxonomy: links to the tagURL (e.g. `/tags/{tag}`) of the static site
+*/
+func replaceTags(content string, titleToSlug map[string]string, t target, config *Config) string {
+	if config.TagLinks == tagLinksKeep {
+		return content
+	}
+	return mapTagLines(content, func(line string) string {
+		return tagRegexp.ReplaceAllStringFunc(line, func(tag string) string {
+			match := tagRegexp.FindStringSubmatch(tag)
+			prefix, name := match[1], tagName(match)
+			var url string
+			if config.TagLinks == tagLinksTaxonomy {
+				url = strings.ReplaceAll(config.TagURL, "{tag}", slugify(name))
+			} else {
+				slug, ok := titleToSlug[normalizePageName(name)]
+				if !ok {
+					return tag
+				}
+				url = t.pageURL(slug)
+			}
+			return fmt.Sprintf("%s[#%s](%s)", prefix, name, url)
+		})
+	})
+}
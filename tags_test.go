@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectTags(t *testing.T) {
+	t.Run("detects simple and multi word tags", func(t *testing.T) {
+		result := detectTags("#first tag, #[[multi word]] and #last.")
+		require.Equal(t, []string{"first", "multi word", "last"}, result)
+	})
+
+	t.Run("ignores headings, anchors and code blocks", func(t *testing.T) {
+		result := detectTags("# heading\n## second heading\n[link](http://example.com/#anchor) not#tag\n```sh\n#comment\n```")
+		require.Empty(t, result)
+	})
+}
+
+func TestMergeInlineTags(t *testing.T) {
+	t.Run("adds new tags from the content", func(t *testing.T) {
+		page := parsedPage{pc: parsedContent{
+			attributes: map[string]string{"tags": "tag1, Another-Tag"},
+			content:    "#tag1 #another-tag #[[new tag]] #new-tag2",
+		}}
+		mergeInlineTags(&page)
+		require.Equal(t, "tag1, Another-Tag, new tag, new-tag2", page.pc.attributes["tags"])
+	})
+
+	t.Run("deduplicates existing tags written as page references", func(t *testing.T) {
+		page := parsedPage{pc: parsedContent{
+			attributes: map[string]string{"tags": "[[Book]], idea"},
+			content:    "#book #[[idea]]",
+		}}
+		mergeInlineTags(&page)
+		require.Equal(t, "Book, idea", page.pc.attributes["tags"])
+	})
+}
+
+func TestReplaceTags(t *testing.T) {
+	titleToSlug := map[string]string{"exported": "exported-page"}
+	content := "#exported and #[[Not Exported]]\n```\n#exported\n```"
+
+	t.Run("keeps tags by default", func(t *testing.T) {
+		require.Equal(t, content, replaceTags(content, titleToSlug, testTarget(), testConfig()))
+	})
+
+	t.Run("links tags to exported pages", func(t *testing.T) {
+		config := testConfig()
+		config.TagLinks = tagLinksPage
+		require.Equal(t, "[#exported](/logseq-pages/exported-page) and #[[Not Exported]]\n```\n#exported\n```", replaceTags(content, titleToSlug, testTarget(), config))
+	})
+
+	t.Run("links tags to taxonomy", func(t *testing.T) {
+		config := testConfig()
+		config.TagLinks = tagLinksTaxonomy
+		config.TagURL = "/categories/{tag}/"
+		require.Equal(t, "[#exported](/categories/exported/) and [#Not Exported](/categories/not-exported/)\n```\n#exported\n```", replaceTags(content, titleToSlug, testTarget(), config))
+	})
+}
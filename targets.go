@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+const (
+	targetHugo   = "hugo"
+	targetJekyll = "jekyll"
+	targetZola   = "zola"
+	targetAstro  = "astro"
+)
+
+/*
+target is the static site generator that consumes the exported pages
+It decides where the pages and assets are stored, what URLs they have and how the front matter looks.
+*/
+type target interface {
+	// pagePath returns the path of the exported page relative to the output folder
+	pagePath(p parsedPage) string
+	// assetsFolder returns the folder for exported assets relative to the output folder
+	assetsFolder() string
+	pageURL(slug string) string
+	assetURL(fileName string) string
+	// frontMatter adds target specific front matter values or changes their structure
+	frontMatter(p parsedPage, values map[string]interface{}) map[string]interface{}
+	// frontMatterFormat is the default front matter format of the target
+	frontMatterFormat() string
+	// image renders an image with the size set in Logseq or the `srcset` of processed images (both are optional)
+	image(alt, url, srcset string, size imageSize) string
+	// media renders an embedded PDF, video, or audio player (kind is mediaPDF, mediaVideo, or mediaAudio)
+	media(kind, url, name string) string
+}
+
+/* sitePaths are the output folders and URL prefixes of the exported pages and assets */
+type sitePaths struct {
+	// pagesDir and assetsDir are relative to the output folder
+	pagesDir        string
+	assetsDir       string
+	pagesURLPrefix  string
+	assetsURLPrefix string
+	// trailingSlash is true for generators that create `page/index.html` and link to `/page/`
+	trailingSlash bool
+}
+
+var defaultSitePaths = map[string]sitePaths{
+	targetHugo:   {"logseq-pages", "logseq-assets", "/logseq-pages", "/logseq-assets", false},
+	targetJekyll: {"logseq-pages", "logseq-assets", "/logseq-pages", "/logseq-assets", true},
+	targetZola:   {filepath.Join("content", "logseq-pages"), filepath.Join("static", "logseq-assets"), "/logseq-pages", "/logseq-assets", true},
+	targetAstro:  {filepath.Join("src", "content", "logseq-pages"), filepath.Join("public", "logseq-assets"), "/logseq-pages", "/logseq-assets", false},
+}
+
+/*
+newTarget creates the target from config
+The pagesRelativePath, assetsRelativePath, webPagesPathPrefix, and webAssetsPathPrefix options override the target defaults.
+*/
+func newTarget(config *Config) (target, error) {
+	paths, ok := defaultSitePaths[config.Target]
+	if !ok {
+		return nil, fmt.Errorf("target has to be one of %q, %q, %q, or %q, got %q", targetHugo, targetJekyll, targetZola, targetAstro, config.Target)
+	}
+	if config.PagesRelativePath != "" {
+		paths.pagesDir = filepath.FromSlash(config.PagesRelativePath)
+	}
+	if config.AssetsRelativePath != "" {
+		paths.assetsDir = filepath.FromSlash(config.AssetsRelativePath)
+	}
+	if config.WebPagesPathPrefix != "" {
+		paths.pagesURLPrefix = config.WebPagesPathPrefix
+	}
+	if config.WebAssetsPathPrefix != "" {
+		paths.assetsURLPrefix = config.WebAssetsPathPrefix
+	}
+	switch config.Target {
+	case targetJekyll:
+		return jekyllTarget{paths}, nil
+	case targetZola:
+		return zolaTarget{paths}, nil
+	case targetAstro:
+		return astroTarget{paths}, nil
+	}
+	return hugoTarget{paths}, nil
+}
+
+func (s sitePaths) pagePath(p parsedPage) string {
+	return filepath.Join(s.pagesDir, p.exportFilename)
+}
+
+func (s sitePaths) assetsFolder() string {
+	return s.assetsDir
+}
+
+func (s sitePaths) pageURL(slug string) string {
+	// we use path here on purpose since we create URL
+	url := path.Join("/", s.pagesURLPrefix, slug)
+	if s.trailingSlash {
+		url += "/"
+	}
+	return url
+}
+
+func (s sitePaths) assetURL(fileName string) string {
+	return path.Join("/", s.assetsURLPrefix, fileName)
+}
+
+/*
+hugoTarget exports pages to `logseq-pages` and assets to `logseq-assets`,
+set pagesRelativePath and assetsRelativePath to export them directly to Hugo `content/` and `static/` folders
+*/
+type hugoTarget struct {
+	sitePaths
+}
+
+func (hugoTarget) frontMatter(p parsedPage, values map[string]interface{}) map[string]interface{} {
+	return values
+}
+
+func (hugoTarget) frontMatterFormat() string {
+	return frontMatterYAML
+}
+
+/*
+image uses the built-in figure shortcode because Hugo removes HTML from Markdown by default
+The figure shortcode doesn't support `srcset`, images with variants use the logseq-image shortcode.
+*/
+func (hugoTarget) image(alt, url, srcset string, size imageSize) string {
+	shortcode := "figure"
+	params := fmt.Sprintf("src=%q", url)
+	if srcset != "" {
+		shortcode = "logseq-image"
+		params += fmt.Sprintf(" srcset=%q", srcset)
+	}
+	params += fmt.Sprintf(" alt=%q", alt)
+	if size.width > 0 {
+		params += fmt.Sprintf(` width="%d"`, size.width)
+	}
+	if size.height > 0 {
+		params += fmt.Sprintf(` height="%d"`, size.height)
+	}
+	return "{{< " + shortcode + " " + params + " >}}"
+}
+
+// media uses the logseq-pdf, logseq-video, and logseq-audio shortcodes because Hugo removes HTML from Markdown by default
+func (hugoTarget) media(kind, url, name string) string {
+	return fmt.Sprintf("{{< logseq-%s src=%q name=%q >}}", kind, url, name)
+}
+
+/*
+jekyllTarget exports pages with a date as posts (`_posts/2023-07-29-slug.md`)
+and all other pages to `logseq-pages`. All pages get a `permalink` so that the page URLs don't depend on the date.
+*/
+type jekyllTarget struct {
+	sitePaths
+}
+
+var jekyllDateRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+func (t jekyllTarget) pagePath(p parsedPage) string {
+	if date := p.pc.attributes["date"]; jekyllDateRegexp.MatchString(date) {
+		return filepath.Join("_posts", fmt.Sprintf("%s-%s.md", date, p.pc.attributes["slug"]))
+	}
+	return filepath.Join(t.pagesDir, p.pc.attributes["slug"]+".md")
+}
+
+func (t jekyllTarget) frontMatter(p parsedPage, values map[string]interface{}) map[string]interface{} {
+	values["permalink"] = t.pageURL(p.pc.attributes["slug"])
+	return values
+}
+
+func (jekyllTarget) frontMatterFormat() string {
+	return frontMatterYAML
+}
+
+// image uses kramdown attributes
+func (jekyllTarget) image(alt, url, srcset string, size imageSize) string {
+	var attributes []string
+	if srcset != "" {
+		attributes = append(attributes, fmt.Sprintf("srcset=%q", srcset))
+	}
+	if size.width > 0 {
+		attributes = append(attributes, fmt.Sprintf(`width="%d"`, size.width))
+	}
+	if size.height > 0 {
+		attributes = append(attributes, fmt.Sprintf(`height="%d"`, size.height))
+	}
+	return fmt.Sprintf("![%s](%s){: %s}", alt, url, strings.Join(attributes, " "))
+}
+
+func (jekyllTarget) media(kind, url, name string) string {
+	return htmlMedia(kind, url, name)
+}
+
+/*
+zolaTarget exports pages to `content/logseq-pages` and assets to `static/logseq-assets`
+Zola only accepts a fixed set of front matter keys, tags go to `[taxonomies]` and all other attributes to `[extra]`.
+*/
+type zolaTarget struct {
+	sitePaths
+}
+
+var zolaFrontMatterKeys = []string{"title", "description", "date", "updated", "weight", "draft", "slug", "path", "aliases", "authors", "template"}
+
+func (zolaTarget) frontMatter(p parsedPage, values map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	extra := map[string]interface{}{}
+	for key, value := range values {
+		switch {
+		case slices.Contains(zolaFrontMatterKeys, key):
+			result[key] = value
+		case key == "tags":
+			result["taxonomies"] = map[string]interface{}{key: value}
+		default:
+			extra[key] = value
+		}
+	}
+	if len(extra) > 0 {
+		result["extra"] = extra
+	}
+	return result
+}
+
+func (zolaTarget) frontMatterFormat() string {
+	return frontMatterTOML
+}
+
+func (zolaTarget) image(alt, url, srcset string, size imageSize) string {
+	return htmlImage(alt, url, srcset, size)
+}
+
+func (zolaTarget) media(kind, url, name string) string {
+	return htmlMedia(kind, url, name)
+}
+
+/*
+astroTarget exports pages to the `src/content/logseq-pages` content collection
+and assets to `public/logseq-assets`
+*/
+type astroTarget struct {
+	sitePaths
+}
+
+func (astroTarget) frontMatter(p parsedPage, values map[string]interface{}) map[string]interface{} {
+	return values
+}
+
+func (astroTarget) frontMatterFormat() string {
+	return frontMatterYAML
+}
+
+func (astroTarget) image(alt, url, srcset string, size imageSize) string {
+	return htmlImage(alt, url, srcset, size)
+}
+
+func (astroTarget) media(kind, url, name string) string {
+	return htmlMedia(kind, url, name)
+}
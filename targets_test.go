@@ -0,0 +1,156 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetPagePath(t *testing.T) {
+	dated := parsedPage{
+		exportFilename: "2023-07-29-slug.md",
+		pc:             parsedContent{attributes: map[string]string{"slug": "slug", "date": "2023-07-29"}},
+	}
+	undated := parsedPage{
+		exportFilename: "slug.md",
+		pc:             parsedContent{attributes: map[string]string{"slug": "slug"}},
+	}
+
+	testCases := []struct {
+		target  string
+		dated   string
+		undated string
+	}{
+		{targetHugo, "logseq-pages/2023-07-29-slug.md", "logseq-pages/slug.md"},
+		{targetJekyll, "_posts/2023-07-29-slug.md", "logseq-pages/slug.md"},
+		{targetZola, "content/logseq-pages/2023-07-29-slug.md", "content/logseq-pages/slug.md"},
+		{targetAstro, "src/content/logseq-pages/2023-07-29-slug.md", "src/content/logseq-pages/slug.md"},
+	}
+	for _, tc := range testCases {
+		config := testConfig()
+		config.Target = tc.target
+		target, err := newTarget(config)
+		require.NoError(t, err)
+		require.Equal(t, tc.dated, target.pagePath(dated))
+		require.Equal(t, tc.undated, target.pagePath(undated))
+	}
+}
+
+func TestNewTarget(t *testing.T) {
+	t.Run("fails for unknown targets", func(t *testing.T) {
+		config := testConfig()
+		config.Target = "gatsby"
+		_, err := newTarget(config)
+		require.Error(t, err)
+	})
+
+	t.Run("uses default paths", func(t *testing.T) {
+		target := testTarget()
+		require.Equal(t, "logseq-assets", target.assetsFolder())
+		require.Equal(t, "/logseq-pages/slug", target.pageURL("slug"))
+		require.Equal(t, "/logseq-assets/image.png", target.assetURL("image.png"))
+	})
+
+	t.Run("uses configured paths", func(t *testing.T) {
+		config := testConfig()
+		config.Target = targetZola
+		config.PagesRelativePath = "content/graph"
+		config.AssetsRelativePath = "static/assets/graph"
+		config.WebPagesPathPrefix = "/graph"
+		config.WebAssetsPathPrefix = "assets/graph/"
+		target, err := newTarget(config)
+		require.NoError(t, err)
+		require.Equal(t, "content/graph/slug.md", target.pagePath(parsedPage{exportFilename: "slug.md"}))
+		require.Equal(t, "static/assets/graph", target.assetsFolder())
+		require.Equal(t, "/graph/slug/", target.pageURL("slug"))
+		require.Equal(t, "/assets/graph/image.png", target.assetURL("image.png"))
+	})
+}
+
+func TestTargetFrontMatter(t *testing.T) {
+	page := parsedPage{pc: parsedContent{attributes: map[string]string{"slug": "hello"}}}
+	values := func() map[string]interface{} {
+		return map[string]interface{}{
+			"title":  "Hello",
+			"slug":   "hello",
+			"tags":   []interface{}{"tag1"},
+			"public": true,
+		}
+	}
+
+	t.Run("jekyll adds permalink", func(t *testing.T) {
+		result := jekyllTarget{defaultSitePaths[targetJekyll]}.frontMatter(page, values())
+		require.Equal(t, "/logseq-pages/hello/", result["permalink"])
+	})
+
+	t.Run("zola moves tags to taxonomies and custom values to extra", func(t *testing.T) {
+		result := zolaTarget{defaultSitePaths[targetZola]}.frontMatter(page, values())
+		require.Equal(t, map[string]interface{}{
+			"title":      "Hello",
+			"slug":       "hello",
+			"taxonomies": map[string]interface{}{"tags": []interface{}{"tag1"}},
+			"extra":      map[string]interface{}{"public": true},
+		}, result)
+	})
+}
+
+func TestTargetImage(t *testing.T) {
+	testCases := []struct {
+		target     string
+		sized      string
+		responsive string
+	}{
+		{
+			targetHugo,
+			`{{< figure src="/logseq-assets/a.png" alt="my \"image\"" width="400" height="300" >}}`,
+			`{{< logseq-image src="/logseq-assets/a.png" srcset="/logseq-assets/a-200w.png 200w, /logseq-assets/a.png 800w" alt="my \"image\"" >}}`,
+		},
+		{
+			targetJekyll,
+			`![my "image"](/logseq-assets/a.png){: width="400" height="300"}`,
+			`![my "image"](/logseq-assets/a.png){: srcset="/logseq-assets/a-200w.png 200w, /logseq-assets/a.png 800w"}`,
+		},
+		{
+			targetZola,
+			`<img src="/logseq-assets/a.png" alt="my &#34;image&#34;" width="400" height="300">`,
+			`<img src="/logseq-assets/a.png" srcset="/logseq-assets/a-200w.png 200w, /logseq-assets/a.png 800w" alt="my &#34;image&#34;">`,
+		},
+		{
+			targetAstro,
+			`<img src="/logseq-assets/a.png" alt="my &#34;image&#34;" width="400" height="300">`,
+			`<img src="/logseq-assets/a.png" srcset="/logseq-assets/a-200w.png 200w, /logseq-assets/a.png 800w" alt="my &#34;image&#34;">`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.target, func(t *testing.T) {
+			config := testConfig()
+			config.Target = tc.target
+			target, err := newTarget(config)
+			require.NoError(t, err)
+			require.Equal(t, tc.sized, target.image(`my "image"`, "/logseq-assets/a.png", "", imageSize{width: 400, height: 300}))
+			require.Equal(t, tc.responsive, target.image(`my "image"`, "/logseq-assets/a.png", "/logseq-assets/a-200w.png 200w, /logseq-assets/a.png 800w", imageSize{}))
+		})
+	}
+}
+
+func TestTargetMedia(t *testing.T) {
+	html := `<video controls src="/logseq-assets/clip.mp4"><a href="/logseq-assets/clip.mp4">clip.mp4</a></video>`
+	testCases := []struct {
+		target   string
+		expected string
+	}{
+		{targetHugo, `{{< logseq-video src="/logseq-assets/clip.mp4" name="clip.mp4" >}}`},
+		{targetJekyll, html},
+		{targetZola, html},
+		{targetAstro, html},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.target, func(t *testing.T) {
+			config := testConfig()
+			config.Target = tc.target
+			target, err := newTarget(config)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, target.media(mediaVideo, "/logseq-assets/clip.mp4", "clip.mp4"))
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"io/fs"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/afero"
+)
+
+// watchDebounce is how long we wait after the last change, Logseq often saves the same file multiple times
+const watchDebounce = 500 * time.Millisecond
+
+/*
+watchGraph exports the graph and re-exports it every time pages, journals, assets, or configuration change
+
+The loaded pages are kept between exports and only the changed files are read again.
+The export renders only the changed pages and the pages that depend on them (see renderPages).
+The config is parsed from args again when export.yaml changes and all pages are loaded again
+when export.yaml or logseq/config.edn changes.
+*/
+func watchGraph(args []string, config *Config) error {
+	appFS := afero.NewOsFs()
+	graphConfig, pages, err := loadGraph(appFS, config.LogseqFolder)
+	if err != nil {
+		return err
+	}
+	if _, err := exportPages(appFS, config, graphConfig, pages); err != nil {
+		return err
+	}
+	// --clean applies only to the first export
+	config.Clean = false
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	for _, folder := range []string{"pages", "journals", "assets"} {
+		addFoldersToWatcher(watcher, filepath.Join(config.LogseqFolder, folder))
+	}
+	// export.yaml and logseq/config.edn
+	for _, folder := range []string{config.LogseqFolder, filepath.Join(config.LogseqFolder, "logseq")} {
+		if err := watcher.Add(folder); err != nil {
+			log.Printf("can't watch %q: %v", folder, err)
+		}
+	}
+
+	changes := make(chan string)
+	go func() {
+		defer close(changes)
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Create) {
+					// new folders have to be watched as well
+					addFoldersToWatcher(watcher, event.Name)
+				}
+				if isWatchedChange(config.LogseqFolder, event) {
+					changes <- event.Name
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("watching the logseq folder failed: %v", err)
+			}
+		}
+	}()
+
+	log.Printf("watching %q for changes", config.LogseqFolder)
+	// reload is set when all pages have to be loaded again, it stays set until the loading succeeds
+	reload := false
+	for changed := range debounce(changes, watchDebounce) {
+		log.Printf("re-exporting the graph, changed files: %s", strings.Join(changed, ", "))
+		for _, name := range changed {
+			switch filepath.Base(name) {
+			case "config.edn":
+				reload = true
+			case "export.yaml":
+				reload = true
+				newConfig, err := parseConfig(args)
+				if err != nil {
+					log.Printf("the configuration could not be parsed, using the previous configuration: %v", err)
+					continue
+				}
+				config = newConfig
+				config.Clean = false
+			}
+		}
+		if reload {
+			graphConfig, pages, err = loadGraph(appFS, config.LogseqFolder)
+		} else {
+			pages, err = reloadPages(appFS, config.LogseqFolder, graphConfig, pages, changed)
+		}
+		if err != nil {
+			log.Printf("export failed: %v", err)
+			reload = true
+			continue
+		}
+		reload = false
+		if _, err := exportPages(appFS, config, graphConfig, pages); err != nil {
+			log.Printf("export failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// addFoldersToWatcher adds the folder with all its subfolders to the watcher
+func addFoldersToWatcher(watcher *fsnotify.Watcher, folder string) {
+	_ = filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != folder {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			log.Printf("can't watch %q: %v", path, err)
+		}
+		return nil
+	})
+}
+
+/*
+isWatchedChange returns true for changes that affect the export
+In the root of the logseq folder and in the `logseq/` folder we only care about the configuration files.
+*/
+func isWatchedChange(logseqFolder string, event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	name := filepath.Base(event.Name)
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+		return false
+	}
+	switch filepath.Dir(event.Name) {
+	case filepath.Clean(logseqFolder):
+		return name == "export.yaml"
+	case filepath.Join(logseqFolder, "logseq"):
+		return name == "config.edn"
+	}
+	return true
+}
+
+/*
+debounce collects values from the input channel until there are no new values for the delay
+and then sends all (deduplicated and sorted) collected values to the output channel
+*/
+func debounce(in <-chan string, delay time.Duration) <-chan []string {
+	out := make(chan []string)
+	go func() {
+		defer close(out)
+		pending := map[string]bool{}
+		timer := time.NewTimer(delay)
+		timer.Stop()
+		flush := func() {
+			if len(pending) == 0 {
+				return
+			}
+			values := make([]string, 0, len(pending))
+			for v := range pending {
+				values = append(values, v)
+			}
+			sort.Strings(values)
+			pending = map[string]bool{}
+			out <- values
+		}
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					timer.Stop()
+					flush()
+					return
+				}
+				pending[v] = true
+				timer.Reset(delay)
+			case <-timer.C:
+				flush()
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebounce(t *testing.T) {
+	in := make(chan string)
+	out := debounce(in, 20*time.Millisecond)
+
+	in <- "b.md"
+	in <- "a.md"
+	in <- "b.md"
+	require.Equal(t, []string{"a.md", "b.md"}, <-out)
+
+	in <- "c.md"
+	close(in)
+	require.Equal(t, []string{"c.md"}, <-out)
+	_, ok := <-out
+	require.False(t, ok)
+}
+
+func TestIsWatchedChange(t *testing.T) {
+	logseqFolder := filepath.Join("/", "graph")
+	testCases := []struct {
+		event    fsnotify.Event
+		expected bool
+	}{
+		{fsnotify.Event{Name: filepath.Join(logseqFolder, "pages", "a.md"), Op: fsnotify.Write}, true},
+		{fsnotify.Event{Name: filepath.Join(logseqFolder, "assets", "image.png"), Op: fsnotify.Create}, true},
+		{fsnotify.Event{Name: filepath.Join(logseqFolder, "pages", "a.md"), Op: fsnotify.Chmod}, false},
+		{fsnotify.Event{Name: filepath.Join(logseqFolder, "pages", ".a.md.swp"), Op: fsnotify.Write}, false},
+		{fsnotify.Event{Name: filepath.Join(logseqFolder, "export.yaml"), Op: fsnotify.Write}, true},
+		{fsnotify.Event{Name: filepath.Join(logseqFolder, "README.md"), Op: fsnotify.Write}, false},
+		{fsnotify.Event{Name: filepath.Join(logseqFolder, "logseq", "config.edn"), Op: fsnotify.Write}, true},
+		{fsnotify.Event{Name: filepath.Join(logseqFolder, "logseq", "custom.css"), Op: fsnotify.Write}, false},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, isWatchedChange(logseqFolder, tc.event), tc.event.String())
+	}
+}